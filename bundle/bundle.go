@@ -0,0 +1,128 @@
+// Package bundle implements a self contained evidence format for proofs
+/*
+A Bundle packages everything needed to show that a document existed at a certain
+time: the timestamp and hashes of the document, the proof.Proof returned by veriff.io,
+optionally the fixpoints of the server and the contents fetched from the references
+of the proof. A Bundle can be saved as versioned JSON or in a compact binary
+encoding and later be verified offline, without contacting veriff.io.
+*/
+package bundle
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"io"
+	"strconv"
+
+	"github.com/veriffio/client-go/proof"
+	"github.com/veriffio/client-go/refcheck"
+	"github.com/veriffio/client-go/webapi"
+	"golang.org/x/crypto/sha3"
+)
+
+// Version is the version of the format written by this package. Version 2 added
+// literals to the operations of the binary format.
+const Version = 2
+
+// A Bundle is the evidence that a document with the given hashes was known at
+// Timestamp.
+type Bundle struct {
+	Version int `json:"version"`
+	// Timestamp in nanoseconds since the unix epoch, as in webapi.ProveResponse
+	Timestamp string      `json:"timestamp"`
+	Sha2_256  []byte      `json:"sha2_256"`
+	Sha3_512  []byte      `json:"sha3_512"`
+	Proof     proof.Proof `json:"proof"`
+	// Fixpoints of the server when the bundle was created
+	Fixpoints []webapi.Fixpoint `json:"fixpoints,omitempty"`
+	// The contents of some or all of the references in the proof
+	References []ReferenceContent `json:"references,omitempty"`
+}
+
+// A ReferenceContent is a copy of what was published at a reference, fetched so
+// that the reference can be checked offline.
+type ReferenceContent struct {
+	Ref     string `json:"ref"`
+	Content []byte `json:"content"`
+}
+
+// FromProveResponse creates a bundle from a provable response of veriff.io.
+func FromProveResponse(r webapi.ProveResponse) (*Bundle, error) {
+	if r.Status != webapi.StatusProvable {
+		return nil, errors.New("the response is not provable")
+	}
+	return &Bundle{
+		Version:   Version,
+		Timestamp: r.Timestamp,
+		Sha2_256:  r.Sha2_256,
+		Sha3_512:  r.Sha3_512,
+		Proof:     r.Proof,
+	}, nil
+}
+
+// AddReference stores the content published at ref in the bundle.
+func (b *Bundle) AddReference(ref string, content []byte) {
+	b.References = append(b.References, ReferenceContent{Ref: ref, Content: content})
+}
+
+// Verify checks that the bundle proves that data existed at the time of the
+// bundle, which is returned in nanoseconds since the unix epoch together with the
+// references of the proof for the sha2_256 and the sha3_512 hash of the data, as
+// from proof.Proof.VerifyAll. If the bundle contains the content of a reference,
+// that content must contain the derived data of the reference as raw bytes, in
+// hex or in base64.
+func (b *Bundle) Verify(data io.Reader) ([][]proof.VerifiedReference, int64, error) {
+	if b.Version < 1 || b.Version > Version {
+		return nil, 0, errors.New("unsupported bundle version " + strconv.Itoa(b.Version))
+	}
+	if data == nil {
+		return nil, 0, errors.New("must provide some data to verify")
+	}
+	h2 := sha256.New()
+	h3 := sha3.New512()
+	n, err := io.Copy(io.MultiWriter(h2, h3), data)
+	if err != nil {
+		return nil, 0, err
+	}
+	if n <= 0 {
+		return nil, 0, errors.New("cannot use empty data")
+	}
+	if !bytes.Equal(h2.Sum(nil), b.Sha2_256) {
+		return nil, 0, errors.New("the data does not match the bundle (sha2_256)")
+	}
+	if !bytes.Equal(h3.Sum(nil), b.Sha3_512) {
+		return nil, 0, errors.New("the data does not match the bundle (sha3_512)")
+	}
+	return b.VerifyHashes()
+}
+
+// VerifyHashes works like Verify but trusts that the hashes in the bundle are
+// those of the document.
+func (b *Bundle) VerifyHashes() ([][]proof.VerifiedReference, int64, error) {
+	ts, err := strconv.ParseInt(b.Timestamp, 10, 64)
+	if err != nil {
+		return nil, 0, errors.New("bad timestamp in bundle")
+	}
+	all, err := b.Proof.VerifyAll(ts, b.Sha2_256, b.Sha3_512)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	for _, rc := range b.References {
+		found := false
+		for _, vr := range append(all[0], all[1]...) {
+			if vr.Ref() != rc.Ref {
+				continue
+			}
+			found = true
+			if !refcheck.Contains(rc.Content, vr.Data()) {
+				return nil, 0, errors.New("the content of reference '" + rc.Ref + "' does not contain the proven data")
+			}
+		}
+		if !found {
+			return nil, 0, errors.New("the bundle contains content for unknown reference '" + rc.Ref + "'")
+		}
+	}
+	return all, ts, nil
+}
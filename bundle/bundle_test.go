@@ -0,0 +1,84 @@
+package bundle
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/veriffio/client-go/proof"
+	"github.com/veriffio/client-go/webapi"
+	"golang.org/x/crypto/sha3"
+)
+
+func testBundle(data string) *Bundle {
+	s2 := sha256.Sum256([]byte(data))
+	s3 := sha3.Sum512([]byte(data))
+	tdata := make([]byte, 8)
+	binary.BigEndian.PutUint64(tdata, 1000)
+	return &Bundle{
+		Version:   Version,
+		Timestamp: "1000",
+		Sha2_256:  s2[:],
+		Sha3_512:  s3[:],
+		Proof: proof.Proof{
+			Data: [][]byte{tdata, s2[:], s3[:]},
+			Operations: []proof.Operation{
+				{Type: proof.SHA3_512, Data: []int{0, 1, 2}},
+				{Type: proof.APPEND, Data: []int{-1}, Literal: []byte("unused")},
+			},
+			References: []proof.Reference{{Data: -1, Ref: "test", Timestamp: time.Unix(2000, 0).UTC()}},
+		},
+		Fixpoints: []webapi.Fixpoint{{Timestamp: "900", Sha2_256: s2[:], Sha3_512: s3[:]}},
+	}
+}
+
+func TestSaveLoad(t *testing.T) {
+	b := testBundle("some data")
+	vr, _, err := b.VerifyHashes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(vr) != 2 || len(vr[0]) != 1 || len(vr[1]) != 1 {
+		t.Fatal("expected the reference once for each hash:", vr)
+	}
+	b.AddReference("test", []byte("published: "+hex.EncodeToString(vr[0][0].Data())))
+
+	for _, f := range []Format{FormatJSON, FormatBinary} {
+		var buf bytes.Buffer
+		if err := Save(&buf, b, f); err != nil {
+			t.Fatal(err)
+		}
+		b2, err := Load(&buf)
+		if err != nil {
+			t.Fatal(f, err)
+		}
+		if !reflect.DeepEqual(b, b2) {
+			t.Errorf("format %d: bundle changed by save and load\n%+v\n%+v", f, b, b2)
+		}
+		if _, ts, err := b2.Verify(strings.NewReader("some data")); err != nil || ts != 1000 {
+			t.Error("format", f, "failed to verify:", ts, err)
+		}
+	}
+}
+
+func TestVerifyFailures(t *testing.T) {
+	b := testBundle("some data")
+	if _, _, err := b.Verify(strings.NewReader("other data")); err == nil {
+		t.Error("verified wrong data")
+	}
+	b.AddReference("test", []byte("something else"))
+	if _, _, err := b.Verify(strings.NewReader("some data")); err == nil {
+		t.Error("verified with reference content missing the data")
+	}
+
+	var buf bytes.Buffer
+	Save(&buf, testBundle("some data"), FormatBinary)
+	if _, err := Load(bytes.NewReader(buf.Bytes()[:buf.Len()-10])); err == nil {
+		t.Error("loaded truncated bundle")
+	}
+}
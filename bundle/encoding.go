@@ -0,0 +1,255 @@
+package bundle
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+
+	"github.com/veriffio/client-go/proof"
+	"github.com/veriffio/client-go/webapi"
+)
+
+// Format selects the encoding used by Save.
+type Format int
+
+// The formats a bundle can be saved in. Load recognizes both.
+const (
+	// Indented JSON, readable by anyone
+	FormatJSON Format = iota
+	// Compact binary encoding starting with the magic bytes "VRFB"
+	FormatBinary
+)
+
+// magic starts every bundle in the binary format.
+var magic = []byte("VRFB")
+
+// maxField limits the size of a single field when loading to avoid huge
+// allocations from corrupt files.
+const maxField = 1 << 26
+
+// Save writes b to w in the given format.
+func Save(w io.Writer, b *Bundle, f Format) error {
+	switch f {
+	case FormatJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(b)
+	case FormatBinary:
+		bw := bufio.NewWriter(w)
+		e := encoder{w: bw}
+		e.encode(b)
+		if e.err != nil {
+			return e.err
+		}
+		return bw.Flush()
+	}
+	return errors.New("unknown bundle format")
+}
+
+// Load reads a bundle written by Save in either format.
+func Load(r io.Reader) (*Bundle, error) {
+	br := bufio.NewReader(r)
+	head, err := br.Peek(len(magic))
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	var b Bundle
+	if bytes.Equal(head, magic) {
+		br.Discard(len(magic))
+		d := decoder{r: br}
+		d.decode(&b)
+		if d.err != nil {
+			return nil, errors.New("corrupt bundle: " + d.err.Error())
+		}
+	} else if err := json.NewDecoder(br).Decode(&b); err != nil {
+		return nil, err
+	}
+	if b.Version < 1 || b.Version > Version {
+		return nil, errors.New("unsupported bundle version")
+	}
+	return &b, nil
+}
+
+// The binary format is the magic bytes followed by the fields of the Bundle in
+// order. Integers are varints, byte slices and strings are prefixed by their
+// length and slices are prefixed by their number of elements.
+
+type encoder struct {
+	w   *bufio.Writer
+	buf [binary.MaxVarintLen64]byte
+	err error
+}
+
+func (e *encoder) encode(b *Bundle) {
+	e.w.Write(magic)
+	e.uint(uint64(b.Version))
+	e.bytes([]byte(b.Timestamp))
+	e.bytes(b.Sha2_256)
+	e.bytes(b.Sha3_512)
+
+	e.uint(uint64(len(b.Proof.Operations)))
+	for _, o := range b.Proof.Operations {
+		e.bytes([]byte(o.Type))
+		e.uint(uint64(len(o.Data)))
+		for _, d := range o.Data {
+			e.int(int64(d))
+		}
+		if b.Version >= 2 {
+			e.bytes(o.Literal)
+		} else if len(o.Literal) > 0 {
+			e.err = errors.New("bundle version 1 cannot contain literals")
+			return
+		}
+	}
+	e.uint(uint64(len(b.Proof.Data)))
+	for _, d := range b.Proof.Data {
+		e.bytes(d)
+	}
+	e.uint(uint64(len(b.Proof.References)))
+	for _, r := range b.Proof.References {
+		e.int(int64(r.Data))
+		t, err := r.Timestamp.MarshalBinary()
+		if err != nil {
+			e.err = err
+			return
+		}
+		e.bytes(t)
+		e.bytes([]byte(r.Ref))
+	}
+
+	e.uint(uint64(len(b.Fixpoints)))
+	for _, f := range b.Fixpoints {
+		e.bytes([]byte(f.Timestamp))
+		e.bytes(f.Sha2_256)
+		e.bytes(f.Sha3_512)
+	}
+	e.uint(uint64(len(b.References)))
+	for _, r := range b.References {
+		e.bytes([]byte(r.Ref))
+		e.bytes(r.Content)
+	}
+}
+
+func (e *encoder) uint(v uint64) {
+	n := binary.PutUvarint(e.buf[:], v)
+	e.write(e.buf[:n])
+}
+
+func (e *encoder) int(v int64) {
+	n := binary.PutVarint(e.buf[:], v)
+	e.write(e.buf[:n])
+}
+
+func (e *encoder) bytes(b []byte) {
+	e.uint(uint64(len(b)))
+	e.write(b)
+}
+
+func (e *encoder) write(b []byte) {
+	if e.err == nil {
+		_, e.err = e.w.Write(b)
+	}
+}
+
+type decoder struct {
+	r   *bufio.Reader
+	err error
+}
+
+func (d *decoder) decode(b *Bundle) {
+	b.Version = int(d.uint())
+	b.Timestamp = string(d.bytes())
+	b.Sha2_256 = d.bytes()
+	b.Sha3_512 = d.bytes()
+
+	// slices are grown while reading so a corrupt count cannot cause a huge allocation
+	for i, n := 0, d.count(); i < n && d.err == nil; i++ {
+		var o proof.Operation
+		o.Type = string(d.bytes())
+		for j, m := 0, d.count(); j < m && d.err == nil; j++ {
+			o.Data = append(o.Data, int(d.int()))
+		}
+		if b.Version >= 2 {
+			if o.Literal = d.bytes(); len(o.Literal) == 0 {
+				o.Literal = nil
+			}
+		}
+		b.Proof.Operations = append(b.Proof.Operations, o)
+	}
+	for i, n := 0, d.count(); i < n && d.err == nil; i++ {
+		b.Proof.Data = append(b.Proof.Data, d.bytes())
+	}
+	for i, n := 0, d.count(); i < n && d.err == nil; i++ {
+		var r proof.Reference
+		r.Data = int(d.int())
+		if err := r.Timestamp.UnmarshalBinary(d.bytes()); err != nil && d.err == nil {
+			d.err = err
+		}
+		r.Ref = string(d.bytes())
+		b.Proof.References = append(b.Proof.References, r)
+	}
+
+	for i, n := 0, d.count(); i < n && d.err == nil; i++ {
+		var f webapi.Fixpoint
+		f.Timestamp = string(d.bytes())
+		f.Sha2_256 = d.bytes()
+		f.Sha3_512 = d.bytes()
+		b.Fixpoints = append(b.Fixpoints, f)
+	}
+	for i, n := 0, d.count(); i < n && d.err == nil; i++ {
+		var r ReferenceContent
+		r.Ref = string(d.bytes())
+		r.Content = d.bytes()
+		b.References = append(b.References, r)
+	}
+}
+
+func (d *decoder) uint() uint64 {
+	if d.err != nil {
+		return 0
+	}
+	var v uint64
+	v, d.err = binary.ReadUvarint(d.r)
+	return v
+}
+
+func (d *decoder) int() int64 {
+	if d.err != nil {
+		return 0
+	}
+	var v int64
+	v, d.err = binary.ReadVarint(d.r)
+	return v
+}
+
+// count reads the number of elements of a slice or bytes of a field.
+func (d *decoder) count() int {
+	n := d.uint()
+	if n > maxField {
+		if d.err == nil {
+			d.err = errors.New("slice too long")
+		}
+		return 0
+	}
+	return int(n)
+}
+
+func (d *decoder) bytes() []byte {
+	n := d.count()
+	if d.err != nil {
+		return nil
+	}
+	// grow with the data actually read rather than trusting n
+	var buf bytes.Buffer
+	if _, err := io.CopyN(&buf, d.r, int64(n)); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		d.err = err
+		return nil
+	}
+	return buf.Bytes()
+}
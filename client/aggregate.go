@@ -0,0 +1,238 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"io"
+	"strconv"
+
+	"github.com/veriffio/client-go/proof"
+	"github.com/veriffio/client-go/webapi"
+	"golang.org/x/crypto/sha3"
+)
+
+// An Aggregate collects many documents into a Merkle tree so that they can be
+// added to veriff.io with a single Add of the root of the tree. A proof for each
+// individual document is later obtained with ProveAggregate.
+//
+// There are two trees, one using sha2_256 and one using sha3_512, so that each
+// hash of a document is chained to veriff.io by its own hash family only. The
+// leaves are the sha2_256 hash of the sha2_256 hash of each document and the
+// sha3_512 hash of its sha3_512 hash, each inner node is the hash of its two
+// children and an odd node at the end of a level is moved up unchanged. The root
+// of the aggregate is the root of the sha2_256 tree followed by the root of the
+// sha3_512 tree. The Aggregate must be rebuilt from the same hashes, in the same
+// order, to prove its documents, so callers should keep them, e.g. by storing the
+// output of Hashes.
+//
+// The zero value is an empty Aggregate ready to use. An Aggregate is not safe for
+// concurrent use.
+type Aggregate struct {
+	docs [][2][]byte
+}
+
+// Add hashes data until EOF and adds it to the aggregate. The index of the
+// document is returned.
+func (a *Aggregate) Add(data io.Reader) (int, error) {
+	if data == nil {
+		return 0, errors.New("data to be added cannot be nil")
+	}
+	s2, s3, err := hashData(context.Background(), data)
+	if err != nil {
+		return 0, err
+	}
+	return a.AddHashes(s2, s3)
+}
+
+// AddHashes adds a document given its sha2_256 and sha3_512 hashes. The index of
+// the document is returned.
+func (a *Aggregate) AddHashes(sha2, sha3 []byte) (int, error) {
+	if len(sha2) != 32 || len(sha3) != 64 {
+		return 0, errors.New("invalid document hashes")
+	}
+	a.docs = append(a.docs, [2][]byte{
+		append([]byte(nil), sha2...),
+		append([]byte(nil), sha3...),
+	})
+	return len(a.docs) - 1, nil
+}
+
+// Len returns the number of documents in the aggregate.
+func (a *Aggregate) Len() int {
+	return len(a.docs)
+}
+
+// Hashes returns the sha2_256 and sha3_512 hashes of document i.
+func (a *Aggregate) Hashes(i int) (sha2, sha3 []byte) {
+	return a.docs[i][0], a.docs[i][1]
+}
+
+// Root returns the root of the Merkle trees, this is the data added to veriff.io.
+// Nil is returned for an empty aggregate.
+func (a *Aggregate) Root() []byte {
+	if len(a.docs) == 0 {
+		return nil
+	}
+	t2, t3 := a.trees()
+	return append(append([]byte(nil), t2[len(t2)-1][0]...), t3[len(t3)-1][0]...)
+}
+
+// trees returns the levels of the sha2_256 and the sha3_512 tree, from the leaves
+// up to the root.
+func (a *Aggregate) trees() (t2, t3 [][][]byte) {
+	l2 := make([][]byte, len(a.docs))
+	l3 := make([][]byte, len(a.docs))
+	for i, d := range a.docs {
+		l2[i], l3[i] = sum2(d[0]), sum3(d[1])
+	}
+	return levels(l2, sum2), levels(l3, sum3)
+}
+
+// levels returns the levels of the tree with the given leaves and inner nodes
+// computed with sum.
+func levels(level [][]byte, sum func(...[]byte) []byte) [][][]byte {
+	res := [][][]byte{level}
+	for len(level) > 1 {
+		next := make([][]byte, 0, (len(level)+1)/2)
+		for i := 0; i < len(level); i += 2 {
+			if i+1 == len(level) {
+				next = append(next, level[i])
+			} else {
+				next = append(next, sum(level[i], level[i+1]))
+			}
+		}
+		level = next
+		res = append(res, level)
+	}
+	return res
+}
+
+// path chains n, the leaf at pos, through tree to its root hashing with op.
+func path(b *proof.Builder, op string, tree [][][]byte, pos int, n proof.Node) proof.Node {
+	for _, level := range tree[:len(tree)-1] {
+		if pos%2 == 1 || pos+1 < len(level) {
+			sib := b.AddData(level[pos^1])
+			if pos%2 == 1 {
+				n = b.Hash(op, sib, n)
+			} else {
+				n = b.Hash(op, n, sib)
+			}
+		}
+		pos /= 2
+	}
+	return n
+}
+
+// Proof returns a proof for document i, chaining its hashes through the Merkle
+// tree to the root and from there into the proof in r, which must be the
+// response from proving the root. The result can be checked with proof.Proof.Verify
+// using either hash of the document and the timestamp of r.
+func (a *Aggregate) Proof(i int, r webapi.ProveResponse) (proof.Proof, error) {
+	if i < 0 || i >= len(a.docs) {
+		return proof.Proof{}, errors.New("no document number " + strconv.Itoa(i))
+	}
+	root := a.Root()
+	s2r, s3r := sum2(root), sum3(root)
+	if !bytes.Equal(s2r, r.Sha2_256) || !bytes.Equal(s3r, r.Sha3_512) {
+		return proof.Proof{}, errors.New("the response is not for the root of the aggregate")
+	}
+
+	var b proof.Builder
+	t2, t3 := a.trees()
+	r2 := path(&b, proof.SHA2_256, t2, i, b.Hash(proof.SHA2_256, b.AddData(a.docs[i][0])))
+	r3 := path(&b, proof.SHA3_512, t3, i, b.Hash(proof.SHA3_512, b.AddData(a.docs[i][1])))
+
+	// the hashes of the root as sent by Add, the root of the other tree is only
+	// data to each of them
+	s2n := b.Hash(proof.SHA2_256, r2, b.AddData(t3[len(t3)-1][0]))
+	s3n := b.Hash(proof.SHA3_512, b.AddData(t2[len(t2)-1][0]), r3)
+
+	// replay the server proof, replacing its inputs for the root hashes by our
+	// calculated outputs so that the chain is unbroken
+	var outs []proof.Node
+	node := func(d int) (proof.Node, error) {
+		switch {
+		case d < 0 && -d <= len(outs):
+			return outs[-d-1], nil
+		case d < 0 || d >= len(r.Proof.Data):
+			return 0, errors.New("invalid proof returned by server")
+		case bytes.Equal(r.Proof.Data[d], s2r):
+			return s2n, nil
+		case bytes.Equal(r.Proof.Data[d], s3r):
+			return s3n, nil
+		}
+		return b.AddData(r.Proof.Data[d]), nil
+	}
+	for _, o := range r.Proof.Operations {
+		in := make([]proof.Node, len(o.Data))
+		for j, d := range o.Data {
+			var err error
+			if in[j], err = node(d); err != nil {
+				return proof.Proof{}, err
+			}
+		}
+		switch o.Type {
+		case proof.PREPEND:
+			outs = append(outs, b.Prepend(o.Literal, in...))
+		case proof.APPEND:
+			outs = append(outs, b.Append(o.Literal, in...))
+		case proof.REVERSE:
+			outs = append(outs, b.Reverse(in...))
+		default:
+			outs = append(outs, b.Hash(o.Type, in...))
+		}
+	}
+	for _, ref := range r.Proof.References {
+		n, err := node(ref.Data)
+		if err != nil {
+			return proof.Proof{}, err
+		}
+		b.Reference(n, ref.Ref, ref.Timestamp)
+	}
+	return b.Proof()
+}
+
+// AddAggregate adds the root of a to veriff.io.
+func (c *Client) AddAggregate(ctx context.Context, a *Aggregate) (Receipt, error) {
+	root := a.Root()
+	if root == nil {
+		return Receipt{}, errors.New("cannot add empty aggregate")
+	}
+	return c.AddContext(ctx, bytes.NewReader(root))
+}
+
+// ProveAggregate requests a proof for the root of a, using the token from
+// AddAggregate, and returns a verified proof for document i together with the timestamp.
+func (c *Client) ProveAggregate(ctx context.Context, a *Aggregate, i int, token []byte) (proof.Proof, int64, error) {
+	root := a.Root()
+	if root == nil {
+		return proof.Proof{}, 0, errors.New("cannot prove empty aggregate")
+	}
+	r, ts, err := c.proveResponse(ctx, sum2(root), sum3(root), token)
+	if err != nil {
+		return proof.Proof{}, 0, err
+	}
+	p, err := a.Proof(i, r)
+	if err != nil {
+		return proof.Proof{}, 0, err
+	}
+	s2, s3 := a.Hashes(i)
+	if _, err := p.VerifyAll(ts, s2, s3); err != nil {
+		return proof.Proof{}, 0, err
+	}
+	return p, ts, nil
+}
+
+// sum2 returns the sha2_256 hash of the concatenation of in.
+func sum2(in ...[]byte) []byte {
+	s := sha256.Sum256(bytes.Join(in, nil))
+	return s[:]
+}
+
+// sum3 returns the sha3_512 hash of the concatenation of in.
+func sum3(in ...[]byte) []byte {
+	s := sha3.Sum512(bytes.Join(in, nil))
+	return s[:]
+}
@@ -0,0 +1,131 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"io"
+	"runtime"
+	"sync"
+	"time"
+)
+
+// A NamedReader is one document to be added by AddMany. The name is only used
+// to identify the corresponding AddResult.
+type NamedReader struct {
+	Name string
+	R    io.Reader
+}
+
+// An AddResult is the outcome of adding one document with AddMany.
+type AddResult struct {
+	Name    string
+	Receipt Receipt
+	Err     error
+}
+
+// BatchOptions controls the parallelism of AddMany.
+type BatchOptions struct {
+	// Number of documents hashed and submitted in parallel, 0 means runtime.NumCPU().
+	Concurrency int
+	// Maximum number of adds sent to veriff.io per second, 0 means no limit.
+	Rate float64
+}
+
+// AddMany adds all documents received on in and sends one AddResult per document
+// on the returned channel, in the order they complete. Readers implementing
+// io.Closer are closed once hashed. The returned channel is closed when in has
+// been closed and all documents are handled, or when ctx is done. When ctx is
+// done, the results of documents already being added are still sent, since a
+// document may have been added and its token would otherwise be lost, so the
+// caller must receive until the channel is closed.
+func (c *Client) AddMany(ctx context.Context, in <-chan NamedReader, opts BatchOptions) <-chan AddResult {
+	n := opts.Concurrency
+	if n <= 0 {
+		n = runtime.NumCPU()
+	}
+	var lim *limiter
+	if opts.Rate > 0 {
+		lim = &limiter{interval: time.Duration(float64(time.Second) / opts.Rate)}
+	}
+
+	out := make(chan AddResult)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				var nr NamedReader
+				var ok bool
+				select {
+				case <-ctx.Done():
+					return
+				case nr, ok = <-in:
+					if !ok {
+						return
+					}
+				}
+				res := AddResult{Name: nr.Name}
+				res.Receipt, res.Err = c.addOne(ctx, nr.R, lim)
+				out <- res
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(out)
+	}()
+	return out
+}
+
+// addOne hashes and adds a single document for AddMany.
+func (c *Client) addOne(ctx context.Context, r io.Reader, lim *limiter) (Receipt, error) {
+	if cl, ok := r.(io.Closer); ok {
+		defer cl.Close()
+	}
+	if r == nil {
+		return Receipt{}, errors.New("data to be sent cannot be nil")
+	}
+	s2, s3, err := hashData(ctx, r)
+	if err != nil {
+		return Receipt{}, err
+	}
+	if lim != nil {
+		if err := lim.wait(ctx); err != nil {
+			return Receipt{}, err
+		}
+	}
+	return c.add(ctx, s2, s3)
+}
+
+// limiter spaces out events by at least interval.
+type limiter struct {
+	mu       sync.Mutex
+	next     time.Time
+	interval time.Duration
+}
+
+// wait blocks until the next event is allowed or ctx is done.
+func (l *limiter) wait(ctx context.Context) error {
+	l.mu.Lock()
+	now := time.Now()
+	t := l.next
+	if t.Before(now) {
+		t = now
+	}
+	l.next = t.Add(l.interval)
+	l.mu.Unlock()
+
+	d := t.Sub(now)
+	if d <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
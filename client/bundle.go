@@ -0,0 +1,38 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"io"
+
+	"github.com/veriffio/client-go/bundle"
+)
+
+// ProveBundle works like ProveContext but returns the proof as a bundle.Bundle
+// which can be saved and verified offline. The current fixpoints of the server are
+// included in the bundle, the contents of the references are not.
+func (c *Client) ProveBundle(ctx context.Context, data io.Reader, token []byte) (*bundle.Bundle, error) {
+	if data == nil {
+		return nil, errors.New("must provide some data to prove")
+	}
+	s2, s3, err := hashData(ctx, data)
+	if err != nil {
+		return nil, err
+	}
+	r, _, err := c.proveResponse(ctx, s2, s3, token)
+	if err != nil {
+		return nil, err
+	}
+	b, err := bundle.FromProveResponse(r)
+	if err != nil {
+		return nil, err
+	}
+	if _, _, err := b.VerifyHashes(); err != nil {
+		return nil, err
+	}
+	b.Fixpoints, err = c.FixpointsContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return b, nil
+}
@@ -3,6 +3,7 @@ package client
 
 import (
 	"bytes"
+	"context"
 	"crypto/sha256"
 	"errors"
 	"io"
@@ -10,7 +11,6 @@ import (
 	"strconv"
 	"time"
 
-	"github.com/veriffio/client-go/proof"
 	"golang.org/x/crypto/sha3"
 
 	"github.com/veriffio/client-go/webapi"
@@ -28,52 +28,129 @@ var (
 )
 
 // A Client represents a simplified way to interact with the proof.io web service.
-// A client is not safe for concurrent use.
+// The configuration of a Client is fixed by New, so a single Client is safe for
+// concurrent use by multiple goroutines and should be reused.
 type Client struct {
-	// If not nil requests will be sent here instead
-	TestHandler http.Handler
-
-	ep string
+	ep        string
+	hc        *http.Client
+	transport http.RoundTripper
+	timeout   time.Duration
+	header    http.Header
+	retry     RetryPolicy
+	store     TokenStore
 }
 
 // New creates a new client connecting to the given endpoint. Use endpoint == "" for the
-// default endpoint.
-func New(endpoint string) *Client {
+// default endpoint. The underlying http.Client, and thereby its connections, is
+// reused for the lifetime of the returned Client.
+func New(endpoint string, opts ...Option) *Client {
 	if endpoint == "" {
 		endpoint = DefaultEndpoint
 	}
-	return &Client{
-		ep: endpoint,
+	c := &Client{
+		ep:     endpoint,
+		header: http.Header{},
+	}
+	for _, o := range opts {
+		o(c)
+	}
+
+	// never modify a http.Client handed to us, it may be shared
+	hc := http.Client{}
+	if c.hc != nil {
+		hc = *c.hc
+	}
+	if c.transport != nil {
+		hc.Transport = c.transport
+	}
+	if c.timeout > 0 {
+		hc.Timeout = c.timeout
 	}
+	c.hc = &hc
+	return c
 }
 
 type req interface {
 	Validate() error
 }
 
-// Add reads data until EOF, hashesh it and sends it to veriff.io.
-func (c *Client) Add(data io.Reader) (token []byte, err error) {
+// A Receipt is the result of adding data to veriff.io. The token is secret and
+// is needed together with the data to later prove it.
+type Receipt struct {
+	Token []byte
+	// The time the data was added, the exact time is part of the proof.
+	ApproximateTime time.Time
+	Sha2_256        []byte
+	Sha3_512        []byte
+}
+
+// Add reads data until EOF, hashesh it and sends it to veriff.io. The hashes echoed
+// by veriff.io are checked against the ones computed locally. If the client has a
+// TokenStore the response is recorded there. If that fails, or the approximate
+// timestamp returned is bad, the receipt is returned together with the error since
+// the data has been added.
+func (c *Client) Add(data io.Reader) (Receipt, error) {
+	return c.AddContext(context.Background(), data)
+}
+
+// AddContext works like Add but stops reading data and aborts the request to
+// veriff.io when ctx is done.
+func (c *Client) AddContext(ctx context.Context, data io.Reader) (Receipt, error) {
 	if data == nil {
-		return nil, errors.New("data to be sent cannot be nil")
+		return Receipt{}, errors.New("data to be sent cannot be nil")
 	}
-	s2, s3, err := hashData(data)
+	s2, s3, err := hashData(ctx, data)
 	if err != nil {
-		return nil, err
+		return Receipt{}, err
 	}
+	return c.add(ctx, s2, s3)
+}
 
+// add does the work of AddContext given the hashes of the data.
+func (c *Client) add(ctx context.Context, s2, s3 []byte) (Receipt, error) {
 	var resp webapi.AddResponse
-	err = c.send(webapi.PathAdd, "POST", webapi.AddRequest{
+	err := c.send(ctx, webapi.PathAdd, "POST", webapi.AddRequest{
 		Sha2_256: s2,
 		Sha3_512: s3,
 	}, &resp)
 	if err != nil {
-		return nil, err
+		return Receipt{}, err
+	}
+	// catch a server mixing up requests now rather than when proving
+	if !bytes.Equal(s2, resp.Sha2_256) {
+		return Receipt{}, errors.New("the server returned a different hash (sha2_256)")
 	}
-	return resp.Token, nil
+	if !bytes.Equal(s3, resp.Sha3_512) {
+		return Receipt{}, errors.New("the server returned a different hash (sha3_512)")
+	}
+	if len(resp.Token) == 0 {
+		return Receipt{}, errors.New("no token returned by server")
+	}
+
+	rc := Receipt{
+		Token:    resp.Token,
+		Sha2_256: s2,
+		Sha3_512: s3,
+	}
+	// the item is added, so the token must not be lost over the informational
+	// timestamp
+	no, tsErr := strconv.ParseInt(resp.ApproximateTimestamp, 10, 64)
+	if tsErr == nil {
+		rc.ApproximateTime = time.Unix(0, no)
+	}
+	if c.store != nil {
+		if err := c.store.Put(resp); err != nil {
+			return rc, errors.New("added but failed to store token: " + err.Error())
+		}
+	}
+	if tsErr != nil {
+		return rc, errors.New("added but bad timestamp returned by server")
+	}
+	return rc, nil
 }
 
 // AddSlice works like Add but for a byte slice.
-func (c *Client) AddSlice(data []byte) (id []byte, err error) {
+func (c *Client) AddSlice(data []byte) (Receipt, error) {
 	return c.Add(bytes.NewBuffer(data))
 }
 
@@ -81,75 +158,99 @@ func (c *Client) AddSlice(data []byte) (id []byte, err error) {
 // that data was added at the given point in time. If veriff.io is not trusted the
 // client may check the external references returned to be sure. If the item
 // has not yet been comitted to veriff.io or is in processing on of the errors
-// defined in this package is returned.
-func (c *Client) Prove(data io.Reader, token []byte) (res []proof.VerifiedReference, timestamp int64, errs error) {
+// defined in this package is returned. The references are grouped by the hash
+// of the data they are proven for.
+func (c *Client) Prove(data io.Reader, token []byte) (ProofResult, error) {
+	return c.ProveContext(context.Background(), data, token)
+}
+
+// ProveContext works like Prove but stops reading data and aborts the request
+// to veriff.io when ctx is done.
+func (c *Client) ProveContext(ctx context.Context, data io.Reader, token []byte) (ProofResult, error) {
 	if data == nil {
-		return nil, 0, errors.New("must provide some data to prove")
+		return ProofResult{}, errors.New("must provide some data to prove")
+	}
+	s2, s3, err := hashData(ctx, data)
+	if err != nil {
+		return ProofResult{}, err
+	}
+	return c.prove(ctx, s2, s3, token)
+}
+
+// prove does the work of ProveContext given the hashes of the data.
+func (c *Client) prove(ctx context.Context, s2, s3, token []byte) (ProofResult, error) {
+	r, ts, err := c.proveResponse(ctx, s2, s3, token)
+	if err != nil {
+		return ProofResult{}, err
 	}
-	s2, s3, err := hashData(data)
+
+	refs, err := r.Proof.VerifyAll(ts, s2, s3)
 	if err != nil {
-		return nil, 0, err
+		return ProofResult{}, err
 	}
+	return NewProofResult(ts, refs), nil
+}
+
+// proveResponse requests a proof for the given hashes and checks that the
+// response is for those hashes and is provable. The proof itself is not verified.
+func (c *Client) proveResponse(ctx context.Context, s2, s3, token []byte) (webapi.ProveResponse, int64, error) {
+	var r webapi.ProveResponse
 	if token == nil {
-		return nil, 0, errors.New("must have a token")
+		return r, 0, errors.New("must have a token")
 	}
 	var pr webapi.ProveRequest
 	if len(token) != 16 {
-		return nil, 0, errors.New("incorrect token provided")
+		return r, 0, errors.New("incorrect token provided")
 	}
 	pr.Token = token
 	pr.Sha2_256 = s2
 
-	var r webapi.ProveResponse
-	err = c.send(webapi.PathProve, "POST", pr, &r)
+	err := c.send(ctx, webapi.PathProve, "POST", pr, &r)
 	if err == ErrStatusNotFound {
-		return nil, 0, ErrStatusNotFound
+		return r, 0, ErrStatusNotFound
 	}
 	if err != nil {
-		return nil, 0, err
+		return r, 0, err
 	}
-	// verify the proof locally and verify that the input used in the proof correspond to the
-	// input we expect based on the hashes we have computed from the data
+	// verify that the input used in the proof correspond to the input we expect based
+	// on the hashes we have computed from the data
 	if bytes.Compare(s2, r.Sha2_256) != 0 {
-		return nil, 0, errors.New("the hash does not match, did you add inconsistent hashes? (sha2_256)")
+		return r, 0, errors.New("the hash does not match, did you add inconsistent hashes? (sha2_256)")
 	}
 	if bytes.Compare(s3, r.Sha3_512) != 0 {
-		return nil, 0, errors.New("the hash does not match, did you add inconsistent hashes? (sha3_512)")
+		return r, 0, errors.New("the hash does not match, did you add inconsistent hashes? (sha3_512)")
 	}
 
 	switch r.Status {
 	case webapi.StatusProvable:
 		break
 	case webapi.StatusInChain:
-		return nil, 0, ErrStatusInChain
+		return r, 0, ErrStatusInChain
 	default:
-		return nil, 0, errors.New("unknown proof status: " + r.Status)
+		return r, 0, errors.New("unknown proof status: " + r.Status)
 	}
 
 	ts, err := strconv.ParseInt(r.Timestamp, 10, 64)
 	if err != nil {
-		return nil, 0, errors.New("bad timestamp returned by server")
+		return r, 0, errors.New("bad timestamp returned by server")
 	}
 
-	refs, err := r.Proof.Verify(s2, ts)
-	if err != nil {
-		return nil, 0, err
-	}
-	refs2, err := r.Proof.Verify(s3, ts)
-	if err != nil {
-		return nil, 0, err
-	}
-	return append(refs, refs2...), ts, nil
+	return r, ts, nil
 }
 
-func (c *Client) ProveSlice(data, id []byte) ([]proof.VerifiedReference, int64, error) {
+func (c *Client) ProveSlice(data, id []byte) (ProofResult, error) {
 	return c.Prove(bytes.NewBuffer(data), id)
 }
 
 func (c *Client) Latest() (sha2, sha3 []byte, ts time.Time, err error) {
+	return c.LatestContext(context.Background())
+}
+
+// LatestContext works like Latest but aborts the request when ctx is done.
+func (c *Client) LatestContext(ctx context.Context) (sha2, sha3 []byte, ts time.Time, err error) {
 
 	var r webapi.LatestResponse
-	err = c.send(webapi.PathLatest, "POST", nil, &r)
+	err = c.send(ctx, webapi.PathLatest, "POST", nil, &r)
 	if err != nil {
 		return
 	}
@@ -164,8 +265,13 @@ func (c *Client) Latest() (sha2, sha3 []byte, ts time.Time, err error) {
 }
 
 func (c *Client) Fixpoints() (fps []webapi.Fixpoint, err error) {
+	return c.FixpointsContext(context.Background())
+}
+
+// FixpointsContext works like Fixpoints but aborts the request when ctx is done.
+func (c *Client) FixpointsContext(ctx context.Context) (fps []webapi.Fixpoint, err error) {
 	var r webapi.FixpointsResponse
-	err = c.send(webapi.PathFixpoints, "POST", nil, &r)
+	err = c.send(ctx, webapi.PathFixpoints, "POST", nil, &r)
 	if err != nil {
 		return
 	}
@@ -175,12 +281,15 @@ func (c *Client) Fixpoints() (fps []webapi.Fixpoint, err error) {
 	return r.Points, nil
 }
 
-// do sha256 and sha3 hash of the data
-func hashData(data io.Reader) ([]byte, []byte, error) {
+// do sha256 and sha3 hash of the data, giving up as soon as ctx is done
+func hashData(ctx context.Context, data io.Reader) ([]byte, []byte, error) {
 	h2 := sha256.New()
-	r := io.TeeReader(data, h2)
+	r := io.TeeReader(ctxReader{ctx, data}, h2)
 	h3 := sha3.New512()
 	n, err := io.Copy(h3, r)
+	if ctx.Err() != nil {
+		return nil, nil, ctx.Err()
+	}
 	if n <= 0 {
 		return nil, nil, errors.New("cannot use empty data")
 	}
@@ -188,3 +297,18 @@ func hashData(data io.Reader) ([]byte, []byte, error) {
 	s3 := h3.Sum(nil)
 	return s2, s3, err
 }
+
+// ctxReader fails any read after ctx is done so that hashing of a large or slow
+// reader can be cancelled. ctx is only checked between reads, a Read that is
+// already blocked is not interrupted.
+type ctxReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (cr ctxReader) Read(p []byte) (int, error) {
+	if err := cr.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return cr.r.Read(p)
+}
@@ -0,0 +1,618 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/veriffio/client-go/proof"
+	"github.com/veriffio/client-go/webapi"
+)
+
+func TestOptionsHeaders(t *testing.T) {
+	var got http.Header
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header
+		w.Write([]byte(`{"fixpoints":[]}`))
+	})
+	c := New("", WithUserAgent("tester/1.0"), WithHeaders(http.Header{"X-Trace": {"abc"}}), WithTestHandler(h))
+	if _, err := c.Fixpoints(); err != nil {
+		t.Fatal(err)
+	}
+	if got.Get("User-Agent") != "tester/1.0" {
+		t.Error("user agent not set:", got.Get("User-Agent"))
+	}
+	if got.Get("X-Trace") != "abc" {
+		t.Error("extra header not set:", got.Get("X-Trace"))
+	}
+	if got.Get("X-Client") != "client-go" {
+		t.Error("client header overwritten:", got.Get("X-Client"))
+	}
+}
+
+func TestRetryServiceUnavailable(t *testing.T) {
+	calls := 0
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls < 3 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{"fixpoints":[]}`))
+	})
+	c := New("", WithRetry(RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond}), WithTestHandler(h))
+	if _, err := c.Fixpoints(); err != nil {
+		t.Fatal(err)
+	}
+	if calls != 3 {
+		t.Error("expected 3 calls, got", calls)
+	}
+
+	calls = -10
+	if _, err := c.Fixpoints(); err != ErrServiceUnavailable {
+		t.Error("expected ErrServiceUnavailable after all attempts, got", err)
+	}
+	if calls != -7 {
+		t.Error("expected 3 more calls, got", calls+10)
+	}
+}
+
+// cancelReader returns zeros forever and cancels after n reads.
+type cancelReader struct {
+	n      int
+	cancel context.CancelFunc
+}
+
+func (cr *cancelReader) Read(p []byte) (int, error) {
+	if cr.n--; cr.n == 0 {
+		cr.cancel()
+	}
+	for i := range p {
+		p[i] = 0
+	}
+	return len(p), nil
+}
+
+func TestContextCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	c := New("")
+	if _, err := c.AddContext(ctx, &cancelReader{n: 10, cancel: cancel}); err != context.Canceled {
+		t.Error("hashing not cancelled:", err)
+	}
+
+	started := make(chan struct{}, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		started <- struct{}{}
+		<-r.Context().Done()
+	}))
+	defer srv.Close()
+	c = New(srv.URL)
+
+	ctx, cancel = context.WithCancel(context.Background())
+	go func() {
+		<-started
+		cancel()
+	}()
+	if _, _, _, err := c.LatestContext(ctx); err != context.Canceled {
+		t.Error("request not cancelled:", err)
+	}
+
+	ctx, cancel = context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := c.FixpointsContext(ctx); err != context.DeadlineExceeded {
+		t.Error("request not aborted at deadline:", err)
+	}
+}
+
+func TestRetryPolicy(t *testing.T) {
+	p := RetryPolicy{MaxAttempts: 3, BaseDelay: time.Second, MaxDelay: 3 * time.Second}
+	if p.retryable(webapi.PathAdd, errors.New("connection reset"), true, 1) {
+		t.Error("add must not be retried after network errors by default")
+	}
+	if !p.retryable(webapi.PathAdd, ErrServiceUnavailable, false, 1) {
+		t.Error("add should be retried when the service is unavailable")
+	}
+	if !p.retryable(webapi.PathProve, errors.New("connection reset"), true, 2) {
+		t.Error("prove should be retried after network errors")
+	}
+	if p.retryable(webapi.PathProve, ErrServiceUnavailable, false, 3) {
+		t.Error("retried more than MaxAttempts")
+	}
+	if d := p.delay(5, 0); d < 1500*time.Millisecond || d > 3*time.Second {
+		t.Error("delay not limited by MaxDelay:", d)
+	}
+	if d := p.delay(1, time.Minute); d != time.Minute {
+		t.Error("Retry-After not honored:", d)
+	}
+	if d := parseRetryAfter("120"); d != 2*time.Minute {
+		t.Error("bad Retry-After parse:", d)
+	}
+}
+
+// proveResponse returns a minimal valid webapi.ProveResponse for data.
+func proveResponse(data []byte, ts int64, status string) webapi.ProveResponse {
+	s2, s3, _ := hashData(context.Background(), bytes.NewReader(data))
+	return hashProveResponse(s2, s3, ts, status)
+}
+
+// hashProveResponse works like proveResponse given the hashes of the data.
+func hashProveResponse(s2, s3 []byte, ts int64, status string) webapi.ProveResponse {
+	tdata := make([]byte, 8)
+	binary.BigEndian.PutUint64(tdata, uint64(ts))
+	return webapi.ProveResponse{
+		Timestamp: strconv.FormatInt(ts, 10),
+		Sha2_256:  s2,
+		Sha3_512:  s3,
+		Status:    status,
+		Proof: proof.Proof{
+			Data:       [][]byte{tdata, s2, s3},
+			Operations: []proof.Operation{{Type: proof.SHA3_512, Data: []int{0, 1, 2}}},
+			References: []proof.Reference{{Data: -1, Ref: "test"}},
+		},
+	}
+}
+
+func TestWaitProvable(t *testing.T) {
+	data := []byte("some data")
+	calls := 0
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		switch calls {
+		case 1:
+			w.WriteHeader(http.StatusNotFound)
+		case 2, 3:
+			json.NewEncoder(w).Encode(proveResponse(data, 1000, webapi.StatusInChain))
+		default:
+			json.NewEncoder(w).Encode(proveResponse(data, 1000, webapi.StatusProvable))
+		}
+	})
+	c := New("", WithTestHandler(h))
+
+	var transitions []string
+	res, err := c.WaitProvable(context.Background(), bytes.NewReader(data), make([]byte, 16), PollPolicy{
+		Interval: time.Millisecond,
+		OnStatus: func(from, to string) {
+			transitions = append(transitions, from+">"+to)
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the single reference is verified for both hashes but only through sha3_512
+	if res.Timestamp != 1000 || len(res.Sha2Refs) != 1 || len(res.Sha3Refs) != 1 || len(res.References) != 1 || len(res.Anchored(AnchorSha3)) != 1 {
+		t.Error("unexpected result", res)
+	}
+	exp := []string{">notfound", "notfound>chained", "chained>provable"}
+	if !reflect.DeepEqual(transitions, exp) {
+		t.Error("unexpected transitions", transitions)
+	}
+}
+
+func TestHistoryPaging(t *testing.T) {
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var hr webapi.HistoryRequest
+		json.NewDecoder(r.Body).Decode(&hr)
+		switch hr.From {
+		case "0":
+			w.Write([]byte(`{"entries":[{"timestamp":"1"},{"timestamp":"2"}],"next":"3"}`))
+		case "3":
+			w.Write([]byte(`{"entries":[{"timestamp":"3"}]}`))
+		default:
+			t.Error("unexpected from", hr.From)
+		}
+	})
+	c := New("", WithTestHandler(h))
+	hist, err := c.History(time.Unix(0, 0), time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hist) != 3 || hist[2].Timestamp != "3" {
+		t.Error("unexpected history", hist)
+	}
+	// a zero from is the beginning too
+	if hist, err = c.History(time.Time{}, time.Time{}); err != nil || len(hist) != 3 {
+		t.Error("unexpected history from zero time", hist, err)
+	}
+}
+
+// Run with -race to check that a single Client may be shared.
+func TestConcurrentUse(t *testing.T) {
+	var mu sync.Mutex
+	added := map[string][]byte{}
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/" + webapi.PathAdd:
+			var ar webapi.AddRequest
+			json.NewDecoder(r.Body).Decode(&ar)
+			mu.Lock()
+			added[string(ar.Sha2_256)] = ar.Sha3_512
+			mu.Unlock()
+			json.NewEncoder(w).Encode(webapi.AddResponse{
+				Token:                make([]byte, 16),
+				ApproximateTimestamp: "1000",
+				Sha2_256:             ar.Sha2_256,
+				Sha3_512:             ar.Sha3_512,
+			})
+		case "/" + webapi.PathProve:
+			var pr webapi.ProveRequest
+			json.NewDecoder(r.Body).Decode(&pr)
+			mu.Lock()
+			s3, ok := added[string(pr.Sha2_256)]
+			mu.Unlock()
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			json.NewEncoder(w).Encode(hashProveResponse(pr.Sha2_256, s3, 1000, webapi.StatusProvable))
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	})
+
+	c := New("http://veriff.test", WithTestHandler(handler), WithRetry(DefaultRetryPolicy))
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			data := []byte("document " + strconv.Itoa(i))
+			rc, err := c.AddSlice(data)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if _, err := c.ProveSlice(data, rc.Token); err != nil {
+				t.Error(err)
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+func TestAddMany(t *testing.T) {
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var ar webapi.AddRequest
+		json.NewDecoder(r.Body).Decode(&ar)
+		json.NewEncoder(w).Encode(webapi.AddResponse{
+			Token:                ar.Sha2_256[:16],
+			ApproximateTimestamp: "1000",
+			Sha2_256:             ar.Sha2_256,
+			Sha3_512:             ar.Sha3_512,
+		})
+	})
+	c := New("", WithTestHandler(h))
+
+	in := make(chan NamedReader)
+	go func() {
+		for i := 0; i < 10; i++ {
+			in <- NamedReader{Name: strconv.Itoa(i), R: strings.NewReader("document " + strconv.Itoa(i))}
+		}
+		in <- NamedReader{Name: "empty", R: strings.NewReader("")}
+		close(in)
+	}()
+
+	names := map[string]bool{}
+	for res := range c.AddMany(context.Background(), in, BatchOptions{Concurrency: 3, Rate: 1000}) {
+		names[res.Name] = true
+		if res.Name == "empty" {
+			if res.Err == nil {
+				t.Error("expected error for empty document")
+			}
+			continue
+		}
+		if res.Err != nil || len(res.Receipt.Token) != 16 {
+			t.Error("unexpected result", res)
+		}
+	}
+	if len(names) != 11 {
+		t.Error("expected 11 results, got", len(names))
+	}
+}
+
+func TestAddManyLimits(t *testing.T) {
+	var mu sync.Mutex
+	running, peak := 0, 0
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		if running++; running > peak {
+			peak = running
+		}
+		mu.Unlock()
+		time.Sleep(5 * time.Millisecond)
+		var ar webapi.AddRequest
+		json.NewDecoder(r.Body).Decode(&ar)
+		json.NewEncoder(w).Encode(webapi.AddResponse{
+			Token:                ar.Sha2_256[:16],
+			ApproximateTimestamp: "1000",
+			Sha2_256:             ar.Sha2_256,
+			Sha3_512:             ar.Sha3_512,
+		})
+		mu.Lock()
+		running--
+		mu.Unlock()
+	})
+	c := New("", WithTestHandler(h))
+	docs := func(n int) <-chan NamedReader {
+		in := make(chan NamedReader, n)
+		for i := 0; i < n; i++ {
+			in <- NamedReader{Name: strconv.Itoa(i), R: strings.NewReader("document " + strconv.Itoa(i))}
+		}
+		close(in)
+		return in
+	}
+
+	for res := range c.AddMany(context.Background(), docs(10), BatchOptions{Concurrency: 2}) {
+		if res.Err != nil {
+			t.Error(res.Err)
+		}
+	}
+	if peak < 1 || peak > 2 {
+		t.Error("expected at most 2 adds in parallel, got", peak)
+	}
+
+	// 6 adds at 50 per second take at least 100ms
+	start := time.Now()
+	for res := range c.AddMany(context.Background(), docs(6), BatchOptions{Concurrency: 6, Rate: 50}) {
+		if res.Err != nil {
+			t.Error(res.Err)
+		}
+	}
+	if d := time.Since(start); d < 100*time.Millisecond {
+		t.Error("rate not limited:", d)
+	}
+}
+
+func TestAddManyCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// the document is added as the caller gives up
+		cancel()
+		var ar webapi.AddRequest
+		json.NewDecoder(r.Body).Decode(&ar)
+		json.NewEncoder(w).Encode(webapi.AddResponse{
+			Token:                ar.Sha2_256[:16],
+			ApproximateTimestamp: "1000",
+			Sha2_256:             ar.Sha2_256,
+			Sha3_512:             ar.Sha3_512,
+		})
+	})
+	c := New("", WithTestHandler(h))
+	in := make(chan NamedReader, 5)
+	for i := 0; i < 5; i++ {
+		in <- NamedReader{Name: strconv.Itoa(i), R: strings.NewReader("document " + strconv.Itoa(i))}
+	}
+	close(in)
+
+	var added int
+	for res := range c.AddMany(ctx, in, BatchOptions{Concurrency: 1}) {
+		if res.Err == nil && len(res.Receipt.Token) == 16 {
+			added++
+		}
+	}
+	if added != 1 {
+		t.Error("expected the receipt of the added document only, got", added)
+	}
+}
+
+func TestAggregateProof(t *testing.T) {
+	var a Aggregate
+	for i := 0; i < 5; i++ {
+		if _, err := a.Add(strings.NewReader("document " + strconv.Itoa(i))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	root := a.Root()
+	r := hashProveResponse(sum2(root), sum3(root), 1000, webapi.StatusProvable)
+
+	for i := 0; i < a.Len(); i++ {
+		p, err := a.Proof(i, r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		s2, s3 := a.Hashes(i)
+		if _, err := p.Verify(s2, 1000); err != nil {
+			t.Error("document", i, "sha2_256:", err)
+		}
+		if _, err := p.Verify(s3, 1000); err != nil {
+			t.Error("document", i, "sha3_512:", err)
+		}
+		if _, err := p.Verify(sum2([]byte("other")), 1000); err == nil {
+			t.Error("proof of document", i, "proves other data")
+		}
+	}
+
+	// with a server keeping the hash families apart, so does the aggregate proof
+	r.Proof = proof.Proof{
+		Data:       [][]byte{sum2(root), sum3(root)},
+		Operations: []proof.Operation{{Type: proof.SHA2_256, Data: []int{0}}, {Type: proof.SHA3_512, Data: []int{1}}},
+		References: []proof.Reference{{Data: -1, Ref: "sha2"}, {Data: -2, Ref: "sha3"}},
+	}
+	for i := 0; i < a.Len(); i++ {
+		p, err := a.Proof(i, r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		s2, s3 := a.Hashes(i)
+		refs, err := p.VerifyAll(0, s2, s3)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(refs[0]) != 1 || refs[0][0].Ref() != "sha2" || !reflect.DeepEqual(refs[0][0].HashFunctions(), []string{proof.SHA2_256}) {
+			t.Error("document", i, "sha2_256 chain uses other hashes", refs[0])
+		}
+		if len(refs[1]) != 1 || refs[1][0].Ref() != "sha3" || !reflect.DeepEqual(refs[1][0].HashFunctions(), []string{proof.SHA3_512}) {
+			t.Error("document", i, "sha3_512 chain uses other hashes", refs[1])
+		}
+	}
+}
+
+func TestFileStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tokens.jsonl")
+	fs, err := OpenFileStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var ar webapi.AddRequest
+		json.NewDecoder(r.Body).Decode(&ar)
+		json.NewEncoder(w).Encode(webapi.AddResponse{
+			Token:                []byte("0123456789abcdef"),
+			ApproximateTimestamp: "1000",
+			Sha2_256:             ar.Sha2_256,
+			Sha3_512:             ar.Sha3_512,
+		})
+	})
+	c := New("", WithTestHandler(h), WithTokenStore(fs))
+	if _, err := c.AddSlice([]byte("some data")); err != nil {
+		t.Fatal(err)
+	}
+
+	// reopen to read back what was persisted
+	fs, err = OpenFileStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s2 := sum2([]byte("some data"))
+	r, err := fs.Get(s2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(r.Token) != "0123456789abcdef" || r.ApproximateTimestamp != "1000" || !bytes.Equal(r.Sha3_512, sum3([]byte("some data"))) {
+		t.Error("unexpected record", r)
+	}
+	if _, err := fs.Get(sum2([]byte("other"))); err != ErrTokenNotFound {
+		t.Error("expected ErrTokenNotFound, got", err)
+	}
+
+	// a record cut short by a crash does not hide the others, nor later records
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Write([]byte(`{"token":"MDEy`))
+	f.Close()
+	if fs, err = OpenFileStore(path); err != nil {
+		t.Fatal("truncated record not tolerated:", err)
+	}
+	c = New("", WithTestHandler(h), WithTokenStore(fs))
+	if _, err := c.AddSlice([]byte("other data")); err != nil {
+		t.Fatal(err)
+	}
+	if fs, err = OpenFileStore(path); err != nil {
+		t.Fatal(err)
+	}
+	for _, d := range []string{"some data", "other data"} {
+		if _, err := fs.Get(sum2([]byte(d))); err != nil {
+			t.Error(d, err)
+		}
+	}
+
+	// but corruption before the last record is reported
+	buf, _ := ioutil.ReadFile(path)
+	ioutil.WriteFile(path, append([]byte("garbage\n"), buf...), 0600)
+	if _, err := OpenFileStore(path); err == nil {
+		t.Error("corrupt store opened")
+	}
+}
+
+func TestAddReceipt(t *testing.T) {
+	swap := false
+	ts := "1500000000000000000"
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var ar webapi.AddRequest
+		json.NewDecoder(r.Body).Decode(&ar)
+		if swap {
+			ar.Sha2_256 = sum2([]byte("someone else's data"))
+		}
+		json.NewEncoder(w).Encode(webapi.AddResponse{
+			Token:                []byte("0123456789abcdef"),
+			ApproximateTimestamp: ts,
+			Sha2_256:             ar.Sha2_256,
+			Sha3_512:             ar.Sha3_512,
+		})
+	})
+	c := New("", WithTestHandler(h))
+	rc, err := c.AddSlice([]byte("some data"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !rc.ApproximateTime.Equal(time.Unix(1500000000, 0)) || !bytes.Equal(rc.Sha2_256, sum2([]byte("some data"))) {
+		t.Error("unexpected receipt", rc)
+	}
+
+	// the token is kept, and stored, despite a bad timestamp
+	ts = ""
+	store := NewMemoryStore()
+	c = New("", WithTestHandler(h), WithTokenStore(store))
+	rc, err = c.AddSlice([]byte("other data"))
+	if err == nil || string(rc.Token) != "0123456789abcdef" || !rc.ApproximateTime.IsZero() {
+		t.Error("unexpected receipt for bad timestamp", rc, err)
+	}
+	if _, err := store.Get(sum2([]byte("other data"))); err != nil {
+		t.Error("token not stored:", err)
+	}
+
+	swap = true
+	if _, err := c.AddSlice([]byte("some data")); err == nil {
+		t.Error("mismatching hash not detected")
+	}
+}
+
+func TestProofResultAnchors(t *testing.T) {
+	data := []byte("some data")
+	r := proveResponse(data, 1000, webapi.StatusProvable)
+	// data is tdata, s2, s3 and operation -1, "test", is sha3_512 of all three
+	r.Proof.Operations = append(r.Proof.Operations,
+		proof.Operation{Type: proof.SHA2_256, Data: []int{0, 1}},
+		proof.Operation{Type: proof.SHA3_512, Data: []int{0, 2}},
+		// the hashes binding the timestamp count as well
+		proof.Operation{Type: proof.SHA2_256, Data: []int{1}},
+		proof.Operation{Type: proof.SHA3_512, Data: []int{2}},
+		proof.Operation{Type: proof.BLAKE2B_256, Data: []int{-4, -5, 0}},
+		proof.Operation{Type: proof.SHA2_256, Data: []int{-1}})
+	r.Proof.References = append(r.Proof.References,
+		proof.Reference{Data: -2, Ref: "sha2"},
+		proof.Reference{Data: -3, Ref: "sha3"},
+		proof.Reference{Data: -6, Ref: "both"},
+		proof.Reference{Data: -7, Ref: "mixed"})
+	h := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		json.NewEncoder(w).Encode(r)
+	})
+	c := New("", WithTestHandler(h))
+
+	res, err := c.ProveSlice(data, make([]byte, 16))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, ar := range res.References {
+		got = append(got, ar.Ref()+":"+ar.Anchor().String())
+	}
+	exp := []string{"test:sha3_512", "sha2:sha2_256", "both:both", "mixed:none", "sha3:sha3_512"}
+	if !reflect.DeepEqual(got, exp) || len(res.Verified()) != 8 {
+		t.Error("unexpected references", got)
+	}
+	if a := res.Anchored(AnchorBoth); len(a) != 1 || a[0].Ref() != "both" {
+		t.Error("unexpected references surviving either break", a)
+	}
+
+	// references verified for both hashes are counted once
+	v := proof.TrustPolicy{TrustedHashes: []string{proof.SHA3_512}, MinReferences: 2}.Evaluate(res.Verified(), res.Timestamp)
+	if !v.Accepted || len(v.Trusted) != 2 || len(v.Reasons) != 3 {
+		t.Errorf("unexpected verdict %+v", v)
+	}
+}
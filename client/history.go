@@ -0,0 +1,62 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/veriffio/client-go/webapi"
+)
+
+// History returns all states the chain has had from the time from up to, but not
+// including, to. A zero from means from the beginning and a zero to means up to
+// the latest state. This may be used to audit
+// the evolution of the chain between two fixpoints.
+func (c *Client) History(from, to time.Time) ([]webapi.HistoryEntry, error) {
+	return c.HistoryContext(context.Background(), from, to)
+}
+
+// HistoryContext works like History but aborts when ctx is done.
+func (c *Client) HistoryContext(ctx context.Context, from, to time.Time) ([]webapi.HistoryEntry, error) {
+	hr := webapi.HistoryRequest{From: "0"}
+	if !from.IsZero() {
+		hr.From = strconv.FormatInt(from.UnixNano(), 10)
+	}
+	if !to.IsZero() {
+		hr.To = strconv.FormatInt(to.UnixNano(), 10)
+	}
+
+	var entries []webapi.HistoryEntry
+	for {
+		r, err := c.HistoryPage(ctx, hr)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, r.Entries...)
+		if r.Next == "" {
+			return entries, nil
+		}
+		// protect against a server that does not move forward
+		next, err := strconv.ParseInt(r.Next, 10, 64)
+		if err != nil {
+			return nil, errors.New("bad next timestamp returned by server")
+		}
+		if cur, _ := strconv.ParseInt(hr.From, 10, 64); next <= cur {
+			return nil, errors.New("history paging did not advance")
+		}
+		hr.From = r.Next
+	}
+}
+
+// HistoryPage requests a single page of history. Most users want History instead.
+func (c *Client) HistoryPage(ctx context.Context, hr webapi.HistoryRequest) (webapi.HistoryResponse, error) {
+	var r webapi.HistoryResponse
+	if err := c.send(ctx, webapi.PathHistory, "POST", hr, &r); err != nil {
+		return webapi.HistoryResponse{}, err
+	}
+	if r.Entries == nil {
+		return webapi.HistoryResponse{}, errors.New("empty response returned")
+	}
+	return r, nil
+}
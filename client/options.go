@@ -0,0 +1,75 @@
+package client
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"time"
+)
+
+// An Option configures a Client when passed to New.
+type Option func(*Client)
+
+// WithHTTPClient makes the client send all requests through hc instead of a
+// client of its own. This allows sharing transports, proxies and TLS settings.
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) {
+		c.hc = hc
+	}
+}
+
+// WithTransport uses rt for all round trips made by the client.
+func WithTransport(rt http.RoundTripper) Option {
+	return func(c *Client) {
+		c.transport = rt
+	}
+}
+
+// WithTimeout limits the time a single request to veriff.io may take,
+// including reading the response body.
+func WithTimeout(d time.Duration) Option {
+	return func(c *Client) {
+		c.timeout = d
+	}
+}
+
+// WithUserAgent sets the User-Agent header of every request.
+func WithUserAgent(ua string) Option {
+	return func(c *Client) {
+		c.header.Set("User-Agent", ua)
+	}
+}
+
+// WithHeaders adds the given headers to every request, for example
+// authentication or tracing headers.
+func WithHeaders(h http.Header) Option {
+	return func(c *Client) {
+		for k, vs := range h {
+			for _, v := range vs {
+				c.header.Add(k, v)
+			}
+		}
+	}
+}
+
+// WithTestHandler makes the client serve all requests in process by h instead of
+// sending them over the network. It is intended for testing.
+func WithTestHandler(h http.Handler) Option {
+	return WithTransport(handlerTransport{h})
+}
+
+// handlerTransport is a http.RoundTripper letting a http.Handler serve requests.
+type handlerTransport struct {
+	h http.Handler
+}
+
+func (ht handlerTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	if err := r.Context().Err(); err != nil {
+		return nil, err
+	}
+	// give the handler a server side request of its own
+	sr := httptest.NewRequest(r.Method, r.URL.String(), r.Body).WithContext(r.Context())
+	sr.Header = r.Header.Clone()
+	rec := httptest.NewRecorder()
+	ht.h.ServeHTTP(rec, sr)
+	return rec.Result(), nil
+}
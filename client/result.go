@@ -0,0 +1,159 @@
+package client
+
+import (
+	"time"
+
+	"github.com/veriffio/client-go/proof"
+)
+
+// An Anchor tells which breaks of a hash family a reference survives.
+type Anchor int
+
+// AnchorSha2 is set for a reference proven for the sha2_256 hash of the data by a
+// chain without any sha3 family hash function, so it survives a break of sha3.
+// AnchorSha3 is set for one proven for the sha3_512 hash by a chain without any
+// sha2 family hash function, surviving a break of sha2. A reference with
+// AnchorBoth survives a break of either family, one with AnchorNone depends on
+// both.
+const (
+	AnchorNone Anchor = 0
+	AnchorSha2 Anchor = 1
+	AnchorSha3 Anchor = 2
+	AnchorBoth Anchor = AnchorSha2 | AnchorSha3
+)
+
+func (a Anchor) String() string {
+	switch a {
+	case AnchorSha2:
+		return "sha2_256"
+	case AnchorSha3:
+		return "sha3_512"
+	case AnchorBoth:
+		return "both"
+	}
+	return "none"
+}
+
+// The hash functions of the sha2 and sha3 families, and those of neither. Any
+// other hash function may belong to either family.
+var (
+	sha2Family = []string{proof.SHA2_256, proof.SHA2_512, proof.SHA2_256D, proof.HASH160}
+	sha3Family = []string{proof.SHA3_256, proof.SHA3_512, proof.KECCAK_256}
+	noFamily   = []string{proof.BLAKE2B_256, proof.BLAKE2B_512}
+)
+
+// avoids tells if none of hashes can belong to family.
+func avoids(hashes, family []string) bool {
+	for _, h := range hashes {
+		if in(family, h) || !in(sha2Family, h) && !in(sha3Family, h) && !in(noFamily, h) {
+			return false
+		}
+	}
+	return true
+}
+
+func in(list []string, s string) bool {
+	for _, e := range list {
+		if e == s {
+			return true
+		}
+	}
+	return false
+}
+
+// An AnchoredReference is a reference as verified for each hash of the data.
+type AnchoredReference struct {
+	// The reference verified for the sha2_256 and the sha3_512 hash of the data,
+	// nil if it does not prove that hash. The hash functions of the two differ.
+	Sha2, Sha3 *proof.VerifiedReference
+}
+
+// Reference returns the reference verified for sha2_256 if any, otherwise the
+// one for sha3_512. Use it for what does not depend on the hash, like Ref, Data
+// and Timestamp.
+func (ar AnchoredReference) Reference() proof.VerifiedReference {
+	if ar.Sha2 != nil {
+		return *ar.Sha2
+	}
+	return *ar.Sha3
+}
+
+// Ref returns the string describing the location of publication.
+func (ar AnchoredReference) Ref() string {
+	return ar.Reference().Ref()
+}
+
+// Anchor tells which breaks of a hash family the reference survives.
+func (ar AnchoredReference) Anchor() Anchor {
+	a := AnchorNone
+	if ar.Sha2 != nil && avoids(ar.Sha2.HashFunctions(), sha3Family) {
+		a |= AnchorSha2
+	}
+	if ar.Sha3 != nil && avoids(ar.Sha3.HashFunctions(), sha2Family) {
+		a |= AnchorSha3
+	}
+	return a
+}
+
+// A ProofResult is the outcome of proving data with Prove.
+type ProofResult struct {
+	// Nanoseconds since the unix epoch when the data was added
+	Timestamp int64
+	// The references verified for the sha2_256 and the sha3_512 hash of the data
+	Sha2Refs, Sha3Refs []proof.VerifiedReference
+	// Every distinct reference once, in the order first verified
+	References []AnchoredReference
+}
+
+// NewProofResult groups the references verified for the sha2_256 and the
+// sha3_512 hash of some data, e.g. by bundle.Bundle.Verify, by reference. It is
+// the same grouping as done by Prove. refs must hold exactly those two lists,
+// otherwise the result has no references.
+func NewProofResult(ts int64, refs [][]proof.VerifiedReference) ProofResult {
+	if len(refs) != 2 {
+		return ProofResult{Timestamp: ts}
+	}
+	res := ProofResult{Timestamp: ts, Sha2Refs: refs[0], Sha3Refs: refs[1]}
+	index := map[string]int{}
+	for i := range refs {
+		for j := range refs[i] {
+			vr := &refs[i][j]
+			k, ok := index[vr.Key()]
+			if !ok {
+				k = len(res.References)
+				index[vr.Key()] = k
+				res.References = append(res.References, AnchoredReference{})
+			}
+			if i == 0 {
+				res.References[k].Sha2 = vr
+			} else {
+				res.References[k].Sha3 = vr
+			}
+		}
+	}
+	return res
+}
+
+// Time returns Timestamp as a time.Time.
+func (pr ProofResult) Time() time.Time {
+	return time.Unix(0, pr.Timestamp)
+}
+
+// Anchored returns the references surviving at least the breaks in a, e.g.
+// Anchored(AnchorBoth) for those surviving a break of either hash family.
+func (pr ProofResult) Anchored(a Anchor) []AnchoredReference {
+	var res []AnchoredReference
+	for _, ar := range pr.References {
+		if ar.Anchor()&a == a {
+			res = append(res, ar)
+		}
+	}
+	return res
+}
+
+// Verified returns the references verified for either hash, suitable for
+// proof.TrustPolicy.Evaluate which counts each reference once.
+func (pr ProofResult) Verified() []proof.VerifiedReference {
+	res := make([]proof.VerifiedReference, 0, len(pr.Sha2Refs)+len(pr.Sha3Refs))
+	return append(append(res, pr.Sha2Refs...), pr.Sha3Refs...)
+}
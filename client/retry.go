@@ -0,0 +1,96 @@
+package client
+
+import (
+	"math"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/veriffio/client-go/webapi"
+)
+
+// A RetryPolicy controls how requests failing with ErrServiceUnavailable or a
+// network error are retried. The zero value never retries.
+type RetryPolicy struct {
+	// Maximum number of attempts including the first one. Values below 2 disable retries.
+	MaxAttempts int
+	// Delay before the first retry, doubled for each following attempt. A random
+	// jitter of up to half the delay is subtracted to spread out clients.
+	BaseDelay time.Duration
+	// Upper limit of the delay between two attempts, 0 means no limit. A Retry-After
+	// header sent by the server is always honored.
+	MaxDelay time.Duration
+	// Adds are not idempotent: an add that failed in transit may still have been
+	// stored by the server, and sending it again gives it a second token. Adds are
+	// therefore only retried after network errors if RetryAdd is set. Adds rejected
+	// with ErrServiceUnavailable are always safe to retry.
+	RetryAdd bool
+}
+
+// DefaultRetryPolicy is a reasonable policy for batch jobs.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 5,
+	BaseDelay:   500 * time.Millisecond,
+	MaxDelay:    30 * time.Second,
+}
+
+// WithRetry makes the client retry failed requests according to p.
+func WithRetry(p RetryPolicy) Option {
+	return func(c *Client) {
+		c.retry = p
+	}
+}
+
+// retryable reports if a request to pth that failed with err in attempt number
+// attempt should be tried again.
+func (p RetryPolicy) retryable(pth string, err error, transient bool, attempt int) bool {
+	if attempt >= p.MaxAttempts {
+		return false
+	}
+	if err == ErrServiceUnavailable {
+		return true
+	}
+	if !transient {
+		return false
+	}
+	return pth != webapi.PathAdd || p.RetryAdd
+}
+
+// delay returns the time to wait after the given attempt.
+func (p RetryPolicy) delay(attempt int, retryAfter time.Duration) time.Duration {
+	d := p.BaseDelay
+	for i := 1; i < attempt && d < math.MaxInt64/2 && (p.MaxDelay <= 0 || d < p.MaxDelay); i++ {
+		d *= 2
+	}
+	if p.MaxDelay > 0 && d > p.MaxDelay {
+		d = p.MaxDelay
+	}
+	if d > 1 {
+		d -= time.Duration(rand.Int63n(int64(d / 2)))
+	}
+	if retryAfter > d {
+		return retryAfter
+	}
+	return d
+}
+
+// parseRetryAfter parses a Retry-After header given either in seconds or as a
+// http date. Zero is returned if the header is missing or malformed.
+func parseRetryAfter(h string) time.Duration {
+	if h == "" {
+		return 0
+	}
+	if s, err := strconv.Atoi(h); err == nil {
+		if s < 0 {
+			return 0
+		}
+		return time.Duration(s) * time.Second
+	}
+	if t, err := http.ParseTime(h); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
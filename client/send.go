@@ -2,17 +2,19 @@ package client
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"io/ioutil"
 	"net/http"
-	"net/http/httptest"
-	"path"
 	"strconv"
+	"time"
 )
 
 // convenience method for sending a request, handling needed headers etc.
-func (c *Client) send(pth string, method string, data req, resp interface{}) error {
+// The request is aborted when ctx is done and retried according to the retry
+// policy of the client.
+func (c *Client) send(ctx context.Context, pth string, method string, data req, resp interface{}) error {
 
 	var buf []byte
 	var err error
@@ -28,55 +30,73 @@ func (c *Client) send(pth string, method string, data req, resp interface{}) err
 		buf = []byte{}
 	}
 
-	var req *http.Request
-	if c.TestHandler == nil {
-		req, err = http.NewRequest(method, path.Join(c.ep, pth), bytes.NewBuffer(buf))
-		if err != nil {
+	for attempt := 1; ; attempt++ {
+		retryAfter, transient, err := c.sendOnce(ctx, pth, method, buf, resp)
+		if err == nil {
+			return nil
+		}
+		// report the cancellation rather than what it did to the request
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if !c.retry.retryable(pth, err, transient, attempt) {
 			return err
 		}
-	} else {
-		tp := c.ep + "/" + pth
-		req = httptest.NewRequest(method, tp, bytes.NewBuffer(buf))
+		t := time.NewTimer(c.retry.delay(attempt, retryAfter))
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return ctx.Err()
+		case <-t.C:
+		}
 	}
+}
 
+// sendOnce performs a single attempt of a request. transient is true if the
+// request failed in transit and retryAfter is set if the server asked us to
+// wait before trying again.
+func (c *Client) sendOnce(ctx context.Context, pth string, method string, buf []byte, resp interface{}) (retryAfter time.Duration, transient bool, err error) {
+	req, err := http.NewRequestWithContext(ctx, method, c.ep+"/"+pth, bytes.NewBuffer(buf))
+	if err != nil {
+		return 0, false, err
+	}
+	for k, vs := range c.header {
+		req.Header[k] = append([]string(nil), vs...)
+	}
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("X-Client", "client-go")
 
-	var re *http.Response
-	if c.TestHandler == nil {
-		client := http.Client{}
-		re, err = client.Do(req)
-		if err != nil {
-			return err
-		}
-	} else {
-		rec := httptest.NewRecorder()
-		c.TestHandler.ServeHTTP(rec, req)
-		re = rec.Result()
+	hc := c.hc
+	if hc == nil {
+		hc = http.DefaultClient
+	}
+	re, err := hc.Do(req)
+	if err != nil {
+		return 0, true, err
 	}
 
 	// Read out the body
 	defer re.Body.Close()
 	body, err := ioutil.ReadAll(re.Body)
 	if err != nil {
-		return err
+		return 0, true, err
 	}
 
 	// 404 is special cased as it may be returned before it has been handled,
 	// although rarely it could happen
 	if re.StatusCode == http.StatusNotFound {
-		return ErrStatusNotFound
+		return 0, false, ErrStatusNotFound
 	}
 
 	// 504 may mean that the server is temporary overloaded, we special case this
 	// mainly for testing, but it may be interesting for others too
 	if re.StatusCode == http.StatusServiceUnavailable {
-		return ErrServiceUnavailable
+		return parseRetryAfter(re.Header.Get("Retry-After")), false, ErrServiceUnavailable
 	}
 
 	// If the status is ok we should be able to parse out the response
 	if re.StatusCode == 200 {
-		return json.Unmarshal(body, resp)
+		return 0, false, json.Unmarshal(body, resp)
 	}
 	if re.StatusCode == 400 {
 		type e struct {
@@ -85,9 +105,9 @@ func (c *Client) send(pth string, method string, data req, resp interface{}) err
 		var ee e
 		err := json.Unmarshal(body, &ee)
 		if err != nil {
-			return err
+			return 0, false, err
 		}
-		return errors.New("400:" + ee.Error)
+		return 0, false, errors.New("400:" + ee.Error)
 	}
-	return errors.New("unexpected response code " + strconv.Itoa(re.StatusCode) + " " + req.URL.String())
+	return 0, false, errors.New("unexpected response code " + strconv.Itoa(re.StatusCode) + " " + req.URL.String())
 }
@@ -0,0 +1,152 @@
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"sync"
+
+	"github.com/veriffio/client-go/webapi"
+)
+
+// ErrTokenNotFound is returned by a TokenStore that has no record for a hash.
+var ErrTokenNotFound = errors.New("no token stored for the hash")
+
+// A TokenStore keeps the responses from Add, which contain the secret token that
+// is needed to later prove the data. Records are keyed by the sha2_256 hash of the
+// data. Implementations must be safe for concurrent use.
+type TokenStore interface {
+	// Put stores r, replacing any earlier record with the same hash.
+	Put(r webapi.AddResponse) error
+	// Get returns the record for the sha2_256 hash or ErrTokenNotFound.
+	Get(sha2 []byte) (webapi.AddResponse, error)
+}
+
+// WithTokenStore makes the client record the response of every successful Add in s.
+func WithTokenStore(s TokenStore) Option {
+	return func(c *Client) {
+		c.store = s
+	}
+}
+
+// MemoryStore is a TokenStore keeping the records in memory only.
+type MemoryStore struct {
+	mu sync.RWMutex
+	m  map[string]webapi.AddResponse
+}
+
+// NewMemoryStore creates an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{m: map[string]webapi.AddResponse{}}
+}
+
+// Put implements TokenStore.
+func (ms *MemoryStore) Put(r webapi.AddResponse) error {
+	if len(r.Sha2_256) == 0 {
+		return errors.New("cannot store record without hash")
+	}
+	ms.mu.Lock()
+	ms.m[string(r.Sha2_256)] = r
+	ms.mu.Unlock()
+	return nil
+}
+
+// Get implements TokenStore.
+func (ms *MemoryStore) Get(sha2 []byte) (webapi.AddResponse, error) {
+	ms.mu.RLock()
+	r, ok := ms.m[string(sha2)]
+	ms.mu.RUnlock()
+	if !ok {
+		return webapi.AddResponse{}, ErrTokenNotFound
+	}
+	return r, nil
+}
+
+// FileStore is a TokenStore persisting the records as JSON lines in a file which
+// is only appended to. When the same hash is stored more than once the last record
+// wins. All records are also kept in memory.
+type FileStore struct {
+	mu   sync.Mutex
+	path string
+	mem  *MemoryStore
+}
+
+// OpenFileStore opens the store in the file at path, creating it if it does not
+// exist. The file is readable by the owner only as the tokens are secret. A last
+// record cut short by a crash during Put, which therefore never returned, is
+// removed from the file, any other malformed record is an error.
+func OpenFileStore(path string) (*FileStore, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	buf, err := ioutil.ReadAll(f)
+	if err != nil {
+		return nil, err
+	}
+
+	fs := &FileStore{path: path, mem: NewMemoryStore()}
+	lines := bytes.Split(buf, []byte{'\n'})
+	for i, l := range lines {
+		if len(l) == 0 {
+			continue
+		}
+		// only the last line lacks its newline
+		last := i == len(lines)-1
+		var r webapi.AddResponse
+		if err := json.Unmarshal(l, &r); err != nil {
+			if !last {
+				return nil, errors.New("corrupt token store " + path + ": " + err.Error())
+			}
+			if err := f.Truncate(int64(len(buf) - len(l))); err != nil {
+				return nil, err
+			}
+			break
+		}
+		if last {
+			if _, err := f.WriteAt([]byte{'\n'}, int64(len(buf))); err != nil {
+				return nil, err
+			}
+		}
+		fs.mem.Put(r)
+	}
+	return fs, nil
+}
+
+// Put implements TokenStore. The record is synced to disk before Put returns.
+func (fs *FileStore) Put(r webapi.AddResponse) error {
+	if len(r.Sha2_256) == 0 {
+		return errors.New("cannot store record without hash")
+	}
+	buf, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	f, err := os.OpenFile(fs.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(buf, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return fs.mem.Put(r)
+}
+
+// Get implements TokenStore.
+func (fs *FileStore) Get(sha2 []byte) (webapi.AddResponse, error) {
+	return fs.mem.Get(sha2)
+}
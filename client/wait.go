@@ -0,0 +1,89 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+
+	"github.com/veriffio/client-go/webapi"
+)
+
+// A PollPolicy controls how often WaitProvable asks veriff.io about an item.
+type PollPolicy struct {
+	// Delay between the first and second poll.
+	Interval time.Duration
+	// Upper limit of the delay between two polls, 0 means no limit.
+	MaxInterval time.Duration
+	// Each delay is the previous one multiplied by Multiplier. Values below 1
+	// give a fixed interval.
+	Multiplier float64
+	// If not nil OnStatus is called each time the status of the item changes, the
+	// first time with from == "". The statuses are the Status constants in webapi.
+	OnStatus func(from, to string)
+}
+
+// DefaultPollPolicy suits items that have just been added.
+var DefaultPollPolicy = PollPolicy{
+	Interval:    10 * time.Second,
+	MaxInterval: 10 * time.Minute,
+	Multiplier:  1.5,
+}
+
+// WaitProvable polls veriff.io until the data added with token is provable and
+// then returns the same as Prove would. While the item is not found or still in
+// the chain, or the service is unavailable, polling continues until ctx is done.
+// Any other error ends the polling.
+func (c *Client) WaitProvable(ctx context.Context, data io.Reader, token []byte, policy PollPolicy) (ProofResult, error) {
+	if data == nil {
+		return ProofResult{}, errors.New("must provide some data to prove")
+	}
+	s2, s3, err := hashData(ctx, data)
+	if err != nil {
+		return ProofResult{}, err
+	}
+	if policy.Interval <= 0 {
+		policy.Interval = DefaultPollPolicy.Interval
+	}
+
+	status := ""
+	interval := policy.Interval
+	for {
+		res, err := c.prove(ctx, s2, s3, token)
+		next := status
+		switch err {
+		case nil:
+			next = webapi.StatusProvable
+		case ErrStatusNotFound:
+			next = webapi.StatusNotFound
+		case ErrStatusInChain:
+			next = webapi.StatusInChain
+		case ErrServiceUnavailable:
+		default:
+			return ProofResult{}, err
+		}
+		if next != status {
+			if policy.OnStatus != nil {
+				policy.OnStatus(status, next)
+			}
+			status = next
+		}
+		if err == nil {
+			return res, nil
+		}
+
+		t := time.NewTimer(interval)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return ProofResult{}, ctx.Err()
+		case <-t.C:
+		}
+		if policy.Multiplier > 1 {
+			interval = time.Duration(float64(interval) * policy.Multiplier)
+		}
+		if policy.MaxInterval > 0 && interval > policy.MaxInterval {
+			interval = policy.MaxInterval
+		}
+	}
+}
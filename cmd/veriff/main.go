@@ -0,0 +1,426 @@
+// Command veriff adds documents to veriff.io and proves when they existed
+/*
+Usage:
+
+	veriff [flags] <command> [arguments]
+
+The commands are:
+
+	add <file>                         add the file and print the secret token
+	prove [-bundle out] <file> -token  prove the file and optionally save a bundle
+	verify <bundle> <file>             verify a saved bundle offline
+	latest                             print the latest state of the chain
+	fixpoints                          print the fixpoints of the server
+
+The global flags are:
+
+	-endpoint url  the veriff.io endpoint, defaults to $VERIFF_ENDPOINT or the public service
+	-json          print the output as JSON instead of text
+	-timeout d     give up after the duration d, for example 30s
+
+A file name of "-" reads standard input. The exit status is 0 on success, 1 on
+errors, 2 on bad usage, 3 if the document is not yet provable and 4 if the
+document is not found.
+*/
+package main
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/veriffio/client-go/bundle"
+	"github.com/veriffio/client-go/client"
+	"github.com/veriffio/client-go/proof"
+)
+
+// Exit statuses
+const (
+	exitOK       = 0
+	exitError    = 1
+	exitUsage    = 2
+	exitInChain  = 3
+	exitNotFound = 4
+)
+
+var errUsage = errors.New("bad usage")
+
+// A cli runs one invocation of the command with its own streams and global flags.
+type cli struct {
+	stdin          io.Reader
+	stdout, stderr io.Writer
+	flags          *flag.FlagSet
+	json           bool
+}
+
+var commands = map[string]func(cl *cli, ctx context.Context, c *client.Client, args []string) error{
+	"add":       (*cli).cmdAdd,
+	"prove":     (*cli).cmdProve,
+	"verify":    (*cli).cmdVerify,
+	"latest":    (*cli).cmdLatest,
+	"fixpoints": (*cli).cmdFixpoints,
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// run runs the command line args, without the program name, and returns the
+// exit status.
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	cl := &cli{stdin: stdin, stdout: stdout, stderr: stderr}
+	cl.flags = flag.NewFlagSet("veriff", flag.ContinueOnError)
+	cl.flags.SetOutput(stderr)
+	cl.flags.Usage = cl.usage
+	endpoint := cl.flags.String("endpoint", os.Getenv("VERIFF_ENDPOINT"), "veriff.io endpoint")
+	cl.flags.BoolVar(&cl.json, "json", false, "print output as JSON")
+	timeout := cl.flags.Duration("timeout", 0, "timeout for the whole command, 0 means none")
+	if err := cl.flags.Parse(args); err == flag.ErrHelp {
+		return exitOK
+	} else if err != nil {
+		return exitUsage
+	}
+
+	if cl.flags.NArg() < 1 {
+		cl.usage()
+		return exitUsage
+	}
+	cmd := commands[cl.flags.Arg(0)]
+	if cmd == nil {
+		fmt.Fprintln(stderr, "veriff: unknown command", cl.flags.Arg(0))
+		cl.usage()
+		return exitUsage
+	}
+
+	ctx := context.Background()
+	if *timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, *timeout)
+		defer cancel()
+	}
+	c := client.New(*endpoint, client.WithUserAgent("veriff-cli"), client.WithRetry(client.DefaultRetryPolicy))
+
+	err := cmd(cl, ctx, c, cl.flags.Args()[1:])
+	if err == errUsage {
+		cl.usage()
+	} else if err != nil {
+		fmt.Fprintln(stderr, "veriff:", err)
+	}
+	return exitCode(err)
+}
+
+func (cl *cli) usage() {
+	fmt.Fprintln(cl.stderr, `usage: veriff [flags] <command> [arguments]
+
+commands:
+  add <file>
+  prove [-bundle out] [-binary] <file> -token <hex>
+  verify <bundle> <file>
+  latest
+  fixpoints
+
+flags:`)
+	cl.flags.PrintDefaults()
+}
+
+func exitCode(err error) int {
+	switch err {
+	case nil:
+		return exitOK
+	case errUsage:
+		return exitUsage
+	case client.ErrStatusInChain:
+		return exitInChain
+	case client.ErrStatusNotFound:
+		return exitNotFound
+	}
+	return exitError
+}
+
+// parse parses the flags of a command allowing them to be mixed with the
+// positional arguments, of which exactly n are required.
+func parse(fs *flag.FlagSet, args []string, n int) ([]string, error) {
+	fs.SetOutput(io.Discard)
+	var pos []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, errUsage
+		}
+		if fs.NArg() == 0 {
+			break
+		}
+		pos = append(pos, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+	if len(pos) != n {
+		return nil, errUsage
+	}
+	return pos, nil
+}
+
+func (cl *cli) open(name string) (io.ReadCloser, error) {
+	if name == "-" {
+		return io.NopCloser(cl.stdin), nil
+	}
+	return os.Open(name)
+}
+
+// output prints v as JSON or calls text to print it for humans.
+func (cl *cli) output(v interface{}, text func()) error {
+	if cl.json {
+		enc := json.NewEncoder(cl.stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	}
+	text()
+	return nil
+}
+
+func formatTime(ns int64) string {
+	return time.Unix(0, ns).UTC().Format(time.RFC3339Nano)
+}
+
+func (cl *cli) cmdAdd(ctx context.Context, c *client.Client, args []string) error {
+	fs := flag.NewFlagSet("add", flag.ContinueOnError)
+	pos, err := parse(fs, args, 1)
+	if err != nil {
+		return err
+	}
+	f, err := cl.open(pos[0])
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	// a receipt with a token comes with an error only if the file was added, so
+	// the token is printed before the error is reported
+	rc, err := c.AddContext(ctx, f)
+	if err != nil && len(rc.Token) == 0 {
+		return err
+	}
+	var added string
+	if !rc.ApproximateTime.IsZero() {
+		added = formatTime(rc.ApproximateTime.UnixNano())
+	}
+	out := struct {
+		Token           string `json:"token"`
+		ApproximateTime string `json:"approximate_time"`
+		Sha2_256        string `json:"sha2_256"`
+		Sha3_512        string `json:"sha3_512"`
+	}{
+		hex.EncodeToString(rc.Token),
+		added,
+		hex.EncodeToString(rc.Sha2_256),
+		hex.EncodeToString(rc.Sha3_512),
+	}
+	if perr := cl.output(out, func() {
+		fmt.Fprintln(cl.stdout, "token:   ", out.Token)
+		fmt.Fprintln(cl.stdout, "added:   ", out.ApproximateTime)
+		fmt.Fprintln(cl.stdout, "sha2_256:", out.Sha2_256)
+		fmt.Fprintln(cl.stdout, "sha3_512:", out.Sha3_512)
+		fmt.Fprintln(cl.stdout, "keep the token secret, it is needed to prove the file")
+	}); perr != nil {
+		return perr
+	}
+	return err
+}
+
+func (cl *cli) cmdProve(ctx context.Context, c *client.Client, args []string) error {
+	fs := flag.NewFlagSet("prove", flag.ContinueOnError)
+	tokenHex := fs.String("token", "", "token returned by add, in hex")
+	out := fs.String("bundle", "", "save the proof as a bundle in this file")
+	binary := fs.Bool("binary", false, "save the bundle in the binary format")
+	pos, err := parse(fs, args, 1)
+	if err != nil {
+		return err
+	}
+	token, err := hex.DecodeString(*tokenHex)
+	if err != nil || len(token) == 0 {
+		return errUsage
+	}
+	f, err := cl.open(pos[0])
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var res client.ProofResult
+	if *out == "" {
+		res, err = c.ProveContext(ctx, f, token)
+		if err != nil {
+			return err
+		}
+	} else {
+		b, err := c.ProveBundle(ctx, f, token)
+		if err != nil {
+			return err
+		}
+		refs, ts, err := b.VerifyHashes()
+		if err != nil {
+			return err
+		}
+		res = client.NewProofResult(ts, refs)
+		format := bundle.FormatJSON
+		if *binary {
+			format = bundle.FormatBinary
+		}
+		if err := saveBundle(*out, b, format); err != nil {
+			return err
+		}
+	}
+	return cl.printReferences(res)
+}
+
+func saveBundle(name string, b *bundle.Bundle, format bundle.Format) error {
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	if err := bundle.Save(f, b, format); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func (cl *cli) cmdVerify(ctx context.Context, c *client.Client, args []string) error {
+	fs := flag.NewFlagSet("verify", flag.ContinueOnError)
+	pos, err := parse(fs, args, 2)
+	if err != nil {
+		return err
+	}
+	bf, err := os.Open(pos[0])
+	if err != nil {
+		return err
+	}
+	defer bf.Close()
+	b, err := bundle.Load(bf)
+	if err != nil {
+		return err
+	}
+	f, err := cl.open(pos[1])
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	refs, ts, err := b.Verify(f)
+	if err != nil {
+		return err
+	}
+	return cl.printReferences(client.NewProofResult(ts, refs))
+}
+
+// printReferences prints each reference of res once, with the hash functions it
+// is verified through for each hash of the file.
+func (cl *cli) printReferences(res client.ProofResult) error {
+	type ref struct {
+		Ref               string   `json:"ref"`
+		Data              string   `json:"data"`
+		Sha2HashFunctions []string `json:"sha2_256_hash_functions,omitempty"`
+		Sha3HashFunctions []string `json:"sha3_512_hash_functions,omitempty"`
+		Published         string   `json:"published,omitempty"`
+		Anchor            string   `json:"anchor"`
+	}
+	out := struct {
+		Timestamp  string `json:"timestamp"`
+		Earliest   string `json:"earliest,omitempty"`
+		References []ref  `json:"references"`
+	}{Timestamp: formatTime(res.Timestamp)}
+	if t, ok := proof.EarliestTime(res.Verified()); ok {
+		out.Earliest = formatTime(t.UnixNano())
+	}
+	for _, ar := range res.References {
+		r := ar.Reference()
+		o := ref{Ref: r.Ref(), Data: hex.EncodeToString(r.Data()), Anchor: ar.Anchor().String()}
+		if ar.Sha2 != nil {
+			o.Sha2HashFunctions = ar.Sha2.HashFunctions()
+		}
+		if ar.Sha3 != nil {
+			o.Sha3HashFunctions = ar.Sha3.HashFunctions()
+		}
+		if !r.Timestamp().IsZero() {
+			o.Published = formatTime(r.Timestamp().UnixNano())
+		}
+		out.References = append(out.References, o)
+	}
+	return cl.output(out, func() {
+		fmt.Fprintln(cl.stdout, "the file existed at", out.Timestamp, "if any of these references contain the data:")
+		for _, r := range out.References {
+			fmt.Fprintln(cl.stdout)
+			fmt.Fprintln(cl.stdout, "  reference:", r.Ref)
+			fmt.Fprintln(cl.stdout, "  data:     ", r.Data)
+			if r.Sha2HashFunctions != nil {
+				fmt.Fprintln(cl.stdout, "  sha2_256: ", r.Sha2HashFunctions)
+			}
+			if r.Sha3HashFunctions != nil {
+				fmt.Fprintln(cl.stdout, "  sha3_512: ", r.Sha3HashFunctions)
+			}
+			fmt.Fprintln(cl.stdout, "  anchor:   ", r.Anchor)
+			if r.Published != "" {
+				fmt.Fprintln(cl.stdout, "  published:", r.Published)
+			}
+		}
+		if out.Earliest != "" {
+			fmt.Fprintln(cl.stdout)
+			fmt.Fprintln(cl.stdout, "proven to exist no later than", out.Earliest)
+		}
+	})
+}
+
+func (cl *cli) cmdLatest(ctx context.Context, c *client.Client, args []string) error {
+	fs := flag.NewFlagSet("latest", flag.ContinueOnError)
+	if _, err := parse(fs, args, 0); err != nil {
+		return err
+	}
+	s2, s3, ts, err := c.LatestContext(ctx)
+	if err != nil {
+		return err
+	}
+	out := struct {
+		Timestamp string `json:"timestamp"`
+		Sha2_256  string `json:"sha2_256"`
+		Sha3_512  string `json:"sha3_512"`
+	}{formatTime(ts.UnixNano()), hex.EncodeToString(s2), hex.EncodeToString(s3)}
+	return cl.output(out, func() {
+		fmt.Fprintln(cl.stdout, "timestamp:", out.Timestamp)
+		fmt.Fprintln(cl.stdout, "sha2_256: ", out.Sha2_256)
+		fmt.Fprintln(cl.stdout, "sha3_512: ", out.Sha3_512)
+	})
+}
+
+func (cl *cli) cmdFixpoints(ctx context.Context, c *client.Client, args []string) error {
+	fs := flag.NewFlagSet("fixpoints", flag.ContinueOnError)
+	if _, err := parse(fs, args, 0); err != nil {
+		return err
+	}
+	fps, err := c.FixpointsContext(ctx)
+	if err != nil {
+		return err
+	}
+	type fixpoint struct {
+		Timestamp string `json:"timestamp"`
+		Sha2_256  string `json:"sha2_256"`
+		Sha3_512  string `json:"sha3_512"`
+	}
+	out := []fixpoint{}
+	for _, fp := range fps {
+		ts := fp.Timestamp
+		if ns, err := strconv.ParseInt(fp.Timestamp, 10, 64); err == nil {
+			ts = formatTime(ns)
+		}
+		out = append(out, fixpoint{ts, hex.EncodeToString(fp.Sha2_256), hex.EncodeToString(fp.Sha3_512)})
+	}
+	return cl.output(out, func() {
+		for _, fp := range out {
+			fmt.Fprintln(cl.stdout, fp.Timestamp, fp.Sha2_256, fp.Sha3_512)
+		}
+	})
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"io/ioutil"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/veriffio/client-go/client"
+	"github.com/veriffio/client-go/veriffiotest"
+)
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		err  error
+		code int
+	}{
+		{nil, exitOK},
+		{errUsage, exitUsage},
+		{client.ErrStatusInChain, exitInChain},
+		{client.ErrStatusNotFound, exitNotFound},
+		{errors.New("other"), exitError},
+	}
+	for _, tt := range tests {
+		if c := exitCode(tt.err); c != tt.code {
+			t.Error(tt.err, "gives", c, "expected", tt.code)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	token := fs.String("token", "", "")
+	pos, err := parse(fs, []string{"a", "-token", "t", "b"}, 2)
+	if err != nil || *token != "t" || !reflect.DeepEqual(pos, []string{"a", "b"}) {
+		t.Error("unexpected parse", pos, *token, err)
+	}
+	if _, err := parse(fs, []string{"a"}, 2); err != errUsage {
+		t.Error("missing argument accepted")
+	}
+	if _, err := parse(fs, []string{"a", "-nosuchflag", "b"}, 2); err != errUsage {
+		t.Error("unknown flag accepted")
+	}
+}
+
+func TestCommands(t *testing.T) {
+	srv := veriffiotest.NewServer()
+	hs := httptest.NewServer(srv)
+	defer hs.Close()
+	dir := t.TempDir()
+	doc := filepath.Join(dir, "doc.txt")
+	if err := ioutil.WriteFile(doc, []byte("some document"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	veriff := func(code int, args ...string) string {
+		var stdout, stderr bytes.Buffer
+		args = append([]string{"-endpoint", hs.URL, "-json"}, args...)
+		if c := run(args, strings.NewReader("some document"), &stdout, &stderr); c != code {
+			t.Fatalf("%v: exit status %d, expected %d: %s", args, c, code, stderr.String())
+		}
+		return stdout.String()
+	}
+
+	var added struct{ Token string }
+	if err := json.Unmarshal([]byte(veriff(exitOK, "add", doc)), &added); err != nil {
+		t.Fatal(err)
+	}
+	veriff(exitInChain, "prove", doc, "-token", added.Token)
+	veriff(exitNotFound, "prove", "-token", "00112233445566778899aabbccddeeff", doc)
+	srv.Publish()
+
+	bundle := filepath.Join(dir, "doc.proof")
+	var proved struct{ References []struct{ Anchor string } }
+	if err := json.Unmarshal([]byte(veriff(exitOK, "prove", "-bundle", bundle, "-", "-token", added.Token)), &proved); err != nil {
+		t.Fatal(err)
+	}
+	if len(proved.References) != 1 || proved.References[0].Anchor != "sha3_512" {
+		t.Error("unexpected references", proved)
+	}
+	if out := veriff(exitOK, "verify", bundle, doc); out != veriff(exitOK, "prove", doc, "-token", added.Token) {
+		t.Error("verify and prove print different references", out)
+	}
+
+	veriff(exitUsage)
+	veriff(exitUsage, "nosuchcommand")
+	veriff(exitUsage, "prove", doc)
+	veriff(exitError, "verify", filepath.Join(dir, "missing"), doc)
+}
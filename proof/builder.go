@@ -0,0 +1,119 @@
+package proof
+
+import (
+	"bytes"
+	"errors"
+	"strconv"
+	"time"
+)
+
+// A Node refers to an element of Proof.Data or the output of an operation in a
+// proof being built. Its value is the index used in Operation.Data.
+type Node int
+
+// A Builder constructs a well-formed Proof without the need to keep track of
+// indexes. Errors, such as an unknown operation or an invalid node, are kept and
+// returned by Proof. The zero value is an empty Builder ready to use.
+type Builder struct {
+	p   Proof
+	err error
+}
+
+// AddData adds data to the proof. Adding the same data again returns the same node.
+func (b *Builder) AddData(data []byte) Node {
+	if len(data) == 0 {
+		b.fail(errors.New("cannot add empty data"))
+		return 0
+	}
+	for i, d := range b.p.Data {
+		if bytes.Equal(d, data) {
+			return Node(i)
+		}
+	}
+	b.p.Data = append(b.p.Data, append([]byte(nil), data...))
+	return Node(len(b.p.Data) - 1)
+}
+
+// Hash adds an operation hashing the concatenation of the inputs with the
+// registered hash function typ.
+func (b *Builder) Hash(typ string, inputs ...Node) Node {
+	if _, ok := LookupOperation(typ); !ok {
+		b.fail(errors.New("unknown operation '" + typ + "'"))
+	}
+	return b.op(typ, nil, inputs)
+}
+
+// Prepend adds an operation outputting literal followed by the inputs.
+func (b *Builder) Prepend(literal []byte, inputs ...Node) Node {
+	return b.op(PREPEND, literal, inputs)
+}
+
+// Append adds an operation outputting the inputs followed by literal.
+func (b *Builder) Append(literal []byte, inputs ...Node) Node {
+	return b.op(APPEND, literal, inputs)
+}
+
+// Reverse adds an operation outputting the inputs in reverse byte order.
+func (b *Builder) Reverse(inputs ...Node) Node {
+	return b.op(REVERSE, nil, inputs)
+}
+
+func (b *Builder) op(typ string, literal []byte, inputs []Node) Node {
+	if len(inputs) == 0 {
+		b.fail(errors.New("operation '" + typ + "' must have an input"))
+	}
+	o := Operation{Type: typ, Data: make([]int, len(inputs))}
+	if len(literal) > 0 {
+		o.Literal = append([]byte(nil), literal...)
+	}
+	for i, n := range inputs {
+		b.check(n)
+		o.Data[i] = int(n)
+	}
+	b.p.Operations = append(b.p.Operations, o)
+	return Node(-len(b.p.Operations))
+}
+
+// Reference records that the output of n was published at ref at time t.
+func (b *Builder) Reference(n Node, ref string, t time.Time) {
+	if n >= 0 {
+		b.fail(errors.New("reference must refer to calculated data"))
+	}
+	if ref == "" {
+		b.fail(errors.New("cannot have empty reference"))
+	}
+	b.check(n)
+	b.p.References = append(b.p.References, Reference{Data: int(n), Timestamp: t, Ref: ref})
+}
+
+// Proof returns the proof built so far or the first error encountered.
+func (b *Builder) Proof() (Proof, error) {
+	if b.err != nil {
+		return Proof{}, b.err
+	}
+	if len(b.p.Data) == 0 {
+		return Proof{}, errors.New("no data in proof")
+	}
+	if len(b.p.References) == 0 {
+		return Proof{}, errors.New("no reference")
+	}
+	p := Proof{
+		Operations: append([]Operation(nil), b.p.Operations...),
+		Data:       append([][]byte(nil), b.p.Data...),
+		References: append([]Reference(nil), b.p.References...),
+	}
+	return p, nil
+}
+
+// check records an error if n does not exist.
+func (b *Builder) check(n Node) {
+	if n >= 0 && int(n) >= len(b.p.Data) || n < 0 && int(-n) > len(b.p.Operations) {
+		b.fail(errors.New("no such node " + strconv.Itoa(int(n))))
+	}
+}
+
+func (b *Builder) fail(err error) {
+	if b.err == nil {
+		b.err = err
+	}
+}
@@ -0,0 +1,149 @@
+package proof
+
+import (
+	"encoding/hex"
+	"errors"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// These constants are the kinds of reference described by a ReferenceLocator.
+// Each kind except KindLegacy has its own syntax for Reference.Ref:
+//
+//	KindWeb           https://example.com/permalink (or http)
+//	KindFile          file:///path/to/file
+//	KindBitcoinTx     bitcoin:tx/<txid> or bitcoin:<network>/tx/<txid>
+//	KindBitcoinBlock  bitcoin:block/<hash> or bitcoin:<network>/block/<hash>
+//	KindGitCommit     git:<repository url>#<commit hash>
+//	KindNewspaper     newspaper:<name>/<yyyy-mm-dd>[/<page>]
+//
+// Any other string is a legacy free-form description.
+const (
+	KindLegacy       = "legacy"
+	KindWeb          = "web"
+	KindFile         = "file"
+	KindBitcoinTx    = "bitcoin-tx"
+	KindBitcoinBlock = "bitcoin-block"
+	KindGitCommit    = "git-commit"
+	KindNewspaper    = "newspaper"
+)
+
+// A ReferenceLocator is the parsed form of Reference.Ref, telling tooling where
+// and how to look for the published data.
+type ReferenceLocator struct {
+	// One of the Kind constants
+	Kind string
+	// The reference as given
+	Raw string
+	// The URL of web and file references
+	URL *url.URL
+	// The lowercase host of a web reference, the network of a Bitcoin reference ("mainnet"
+	// if not given), the repository of a git commit or the name of a newspaper
+	Source string
+	// The transaction id or block hash, the commit hash or the date of the newspaper
+	// issue as yyyy-mm-dd
+	ID string
+	// The page of a newspaper issue, 0 if not given
+	Page int
+}
+
+// ParseReference parses a reference. Strings not using any of the known schemes
+// are returned as KindLegacy, but an error is returned for a known scheme with a
+// malformed reference.
+func ParseReference(ref string) (ReferenceLocator, error) {
+	l := ReferenceLocator{Kind: KindLegacy, Raw: ref}
+	i := strings.Index(ref, ":")
+	if i <= 0 {
+		return l, nil
+	}
+	scheme, rest := strings.ToLower(ref[:i]), ref[i+1:]
+
+	switch scheme {
+	case "http", "https", "file":
+		u, err := url.Parse(ref)
+		if err != nil {
+			return l, errors.New("malformed reference: " + err.Error())
+		}
+		if scheme == "file" {
+			if u.Path == "" {
+				return l, errors.New("malformed reference: file reference without path")
+			}
+			l.Kind = KindFile
+		} else {
+			if u.Host == "" {
+				return l, errors.New("malformed reference: web reference without host")
+			}
+			l.Kind = KindWeb
+			l.Source = strings.ToLower(u.Hostname())
+		}
+		l.URL = u
+	case "bitcoin":
+		parts := strings.Split(rest, "/")
+		l.Source = "mainnet"
+		if len(parts) == 3 {
+			l.Source, parts = parts[0], parts[1:]
+		}
+		if len(parts) != 2 || !isHex(parts[1], 64, 64) {
+			return l, errors.New("malformed reference: bad bitcoin reference")
+		}
+		switch parts[0] {
+		case "tx":
+			l.Kind = KindBitcoinTx
+		case "block":
+			l.Kind = KindBitcoinBlock
+		default:
+			return l, errors.New("malformed reference: unknown bitcoin reference " + parts[0])
+		}
+		l.ID = strings.ToLower(parts[1])
+	case "git":
+		j := strings.LastIndex(rest, "#")
+		if j <= 0 || !isHex(rest[j+1:], 7, 64) {
+			return l, errors.New("malformed reference: bad git reference")
+		}
+		l.Kind = KindGitCommit
+		l.Source, l.ID = rest[:j], strings.ToLower(rest[j+1:])
+	case "newspaper":
+		parts := strings.Split(rest, "/")
+		if len(parts) < 2 || len(parts) > 3 || parts[0] == "" {
+			return l, errors.New("malformed reference: bad newspaper reference")
+		}
+		if _, err := time.Parse("2006-01-02", parts[1]); err != nil {
+			return l, errors.New("malformed reference: bad newspaper date")
+		}
+		name, err := url.PathUnescape(parts[0])
+		if err != nil {
+			return l, errors.New("malformed reference: bad newspaper name")
+		}
+		if len(parts) == 3 {
+			if l.Page, err = strconv.Atoi(parts[2]); err != nil || l.Page < 1 {
+				return l, errors.New("malformed reference: bad newspaper page")
+			}
+		}
+		l.Kind = KindNewspaper
+		l.Source, l.ID = name, parts[1]
+	}
+	return l, nil
+}
+
+// String returns the reference as given.
+func (l ReferenceLocator) String() string {
+	return l.Raw
+}
+
+// Locator parses the reference, see ParseReference.
+func (vr VerifiedReference) Locator() (ReferenceLocator, error) {
+	return ParseReference(vr.ref)
+}
+
+func isHex(s string, min, max int) bool {
+	if len(s) < min || len(s) > max {
+		return false
+	}
+	if len(s)%2 == 1 {
+		s += "0"
+	}
+	_, err := hex.DecodeString(s)
+	return err == nil
+}
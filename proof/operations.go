@@ -2,21 +2,111 @@ package proof
 
 import (
 	"crypto/sha256"
+	"crypto/sha512"
+	"errors"
+	"sort"
+	"sync"
 
+	"golang.org/x/crypto/blake2b"
+	"golang.org/x/crypto/ripemd160"
 	"golang.org/x/crypto/sha3"
 )
 
 // These constants define the hash functions which are recognized by the package
 // and the strings that must be used to identify them. The functions are
-// implemented as defined in FIPS 180-4 and FIPS-202.
+// implemented as defined in FIPS 180-4 and FIPS-202, BLAKE2b as in RFC 7693 and
+// Keccak-256 as in the original Keccak submission, used by Ethereum. SHA2_256D
+// (sha2_256 applied twice) and HASH160 (ripemd160 of sha2_256) are used by Bitcoin.
 const (
-	SHA2_256 = "sha2_256"
-	SHA3_512 = "sha3_512"
+	SHA2_256    = "sha2_256"
+	SHA3_512    = "sha3_512"
+	SHA2_512    = "sha2_512"
+	SHA3_256    = "sha3_256"
+	BLAKE2B_256 = "blake2b_256"
+	BLAKE2B_512 = "blake2b_512"
+	KECCAK_256  = "keccak256"
+	SHA2_256D   = "sha2_256d"
+	HASH160     = "hash160"
 )
 
-var operations = map[string]func([]byte) []byte{
-	SHA2_256: opSha2_256,
-	SHA3_512: opSha3_512,
+// These constants define the literal operations, which are not hash functions
+// and therefore never listed by VerifiedReference.HashFunctions. PREPEND outputs
+// Operation.Literal followed by the input, APPEND the input followed by the
+// literal and REVERSE the input in reverse byte order, as needed for the
+// byte order of hashes in Bitcoin.
+const (
+	PREPEND = "prepend"
+	APPEND  = "append"
+	REVERSE = "reverse"
+)
+
+var literalOperations = map[string]func(in, literal []byte) []byte{
+	PREPEND: opPrepend,
+	APPEND:  opAppend,
+	REVERSE: opReverse,
+}
+
+// A HashFunc describes a hash function that may be used as the type of an
+// Operation once registered with RegisterOperation.
+type HashFunc struct {
+	// The name used as Operation.Type
+	Name string
+	// Func returns the hash of its input and must be safe for concurrent use
+	Func func([]byte) []byte
+	// Size of the output in bytes
+	Size int
+	// Security level in bits against collisions, as commonly estimated
+	SecurityBits int
+}
+
+var (
+	opMu       sync.RWMutex
+	operations = map[string]HashFunc{
+		SHA2_256:    {Name: SHA2_256, Func: opSha2_256, Size: 32, SecurityBits: 128},
+		SHA3_512:    {Name: SHA3_512, Func: opSha3_512, Size: 64, SecurityBits: 256},
+		SHA2_512:    {Name: SHA2_512, Func: opSha2_512, Size: 64, SecurityBits: 256},
+		SHA3_256:    {Name: SHA3_256, Func: opSha3_256, Size: 32, SecurityBits: 128},
+		BLAKE2B_256: {Name: BLAKE2B_256, Func: opBlake2b_256, Size: 32, SecurityBits: 128},
+		BLAKE2B_512: {Name: BLAKE2B_512, Func: opBlake2b_512, Size: 64, SecurityBits: 256},
+		KECCAK_256:  {Name: KECCAK_256, Func: opKeccak256, Size: 32, SecurityBits: 128},
+		SHA2_256D:   {Name: SHA2_256D, Func: opSha2_256d, Size: 32, SecurityBits: 128},
+		HASH160:     {Name: HASH160, Func: opHash160, Size: 20, SecurityBits: 80},
+	}
+)
+
+// RegisterOperation makes a hash function available to all proofs verified by
+// this package. A name can only be registered once.
+func RegisterOperation(h HashFunc) error {
+	if h.Name == "" || h.Func == nil {
+		return errors.New("a hash function must have a name and a function")
+	}
+	opMu.Lock()
+	defer opMu.Unlock()
+	if _, ok := operations[h.Name]; ok || literalOperations[h.Name] != nil {
+		return errors.New("operation '" + h.Name + "' is already registered")
+	}
+	operations[h.Name] = h
+	return nil
+}
+
+// LookupOperation returns the registered hash function with the given name.
+func LookupOperation(name string) (HashFunc, bool) {
+	opMu.RLock()
+	h, ok := operations[name]
+	opMu.RUnlock()
+	return h, ok
+}
+
+// Operations returns the sorted names of all registered hash functions.
+func Operations() []string {
+	opMu.RLock()
+	names := make([]string, 0, len(operations))
+	for n := range operations {
+		names = append(names, n)
+	}
+	opMu.RUnlock()
+	sort.Strings(names)
+	return names
 }
 
 func opSha2_256(in []byte) []byte {
@@ -27,3 +117,49 @@ func opSha3_512(in []byte) []byte {
 	sum := sha3.Sum512(in)
 	return sum[:]
 }
+func opSha2_512(in []byte) []byte {
+	sum := sha512.Sum512(in)
+	return sum[:]
+}
+func opSha3_256(in []byte) []byte {
+	sum := sha3.Sum256(in)
+	return sum[:]
+}
+func opBlake2b_256(in []byte) []byte {
+	sum := blake2b.Sum256(in)
+	return sum[:]
+}
+func opBlake2b_512(in []byte) []byte {
+	sum := blake2b.Sum512(in)
+	return sum[:]
+}
+func opKeccak256(in []byte) []byte {
+	h := sha3.NewLegacyKeccak256()
+	h.Write(in)
+	return h.Sum(nil)
+}
+func opSha2_256d(in []byte) []byte {
+	sum := sha256.Sum256(in)
+	sum = sha256.Sum256(sum[:])
+	return sum[:]
+}
+func opHash160(in []byte) []byte {
+	sum := sha256.Sum256(in)
+	h := ripemd160.New()
+	h.Write(sum[:])
+	return h.Sum(nil)
+}
+
+func opPrepend(in, literal []byte) []byte {
+	return append(append(make([]byte, 0, len(literal)+len(in)), literal...), in...)
+}
+func opAppend(in, literal []byte) []byte {
+	return append(append(make([]byte, 0, len(in)+len(literal)), in...), literal...)
+}
+func opReverse(in, _ []byte) []byte {
+	out := make([]byte, len(in))
+	for i, b := range in {
+		out[len(in)-1-i] = b
+	}
+	return out
+}
@@ -20,7 +20,8 @@ publically available it may also be verified by the client if desired.
 
 Please note that this package will not verify that the referred references really
 contain the derived data stream. It is up to the client to decide which external
-sources to trust and check that the data is published there.
+sources to trust and check that the data is published there, for example with
+the checkers in the refcheck package.
 
 See also the example in this package.
 */
@@ -43,14 +44,18 @@ type Proof struct {
 }
 
 // An Operation represents a hash operation of input data and is only used as part of
-// a Proof.
+// a Proof. The literal operations PREPEND, APPEND and REVERSE rearrange their
+// input instead of hashing it, which allows a hash to be embedded in a larger
+// structure, such as a block header, without adding every fragment to Proof.Data.
 type Operation struct {
-	// Must equal one of the constants from this package
+	// Must equal one of the constants from this package or a registered operation
 	Type string `json:"type"`
 	// The input data to the hash operation is created by concatenating the slices of bytes
 	// from the appended Proof.Data slice indexed by the indexes
 	// in this slice. len(Data) must be larger than 0.
 	Data []int `json:"data"`
+	// The bytes added by PREPEND and APPEND, must be empty for other operations
+	Literal []byte `json:"literal,omitempty"`
 }
 
 // A Reference specifies the location where original or derived data is published.
@@ -59,16 +64,18 @@ type Reference struct {
 	Data int `json:"data"`
 	// Approximate timestamp when this was published at the reference
 	Timestamp time.Time `json:"timestamp"`
-	// Descriptive reference to the source. For example an URL permalink
+	// Reference to the source. For example an URL permalink, see ParseReference
+	// for the structured forms that tooling understands
 	Ref string `json:"ref"`
 }
 
 // A VerifiedReference is used to hold the output from Verify which may be checked
 // to prove that the provided input data was known at the time of publication.
 type VerifiedReference struct {
-	data   []byte
-	ref    string
-	hashes []string
+	data      []byte
+	ref       string
+	hashes    []string
+	published time.Time
 }
 
 // Data returns the data that should be found at the reference.
@@ -95,3 +102,30 @@ func (vr VerifiedReference) Ref() string {
 func (vr VerifiedReference) HashFunctions() []string {
 	return vr.hashes
 }
+
+// Key identifies the reference regardless of the input and the hash functions it
+// was verified with. References with the same Key are the same publication.
+func (vr VerifiedReference) Key() string {
+	return vr.ref + "\x00" + string(vr.data) + "\x00" + vr.published.UTC().Format(time.RFC3339Nano)
+}
+
+// Timestamp returns the approximate time of publication at the reference, the
+// zero time if the proof does not tell.
+func (vr VerifiedReference) Timestamp() time.Time {
+	return vr.published
+}
+
+// EarliestTime returns the earliest publication time of the references. This is
+// the latest time the input data is proven to have existed, given that the
+// reference is trusted. ok is false if no reference has a publication time.
+func EarliestTime(refs []VerifiedReference) (t time.Time, ok bool) {
+	for _, vr := range refs {
+		if vr.published.IsZero() {
+			continue
+		}
+		if !ok || vr.published.Before(t) {
+			t, ok = vr.published, true
+		}
+	}
+	return t, ok
+}
@@ -6,6 +6,7 @@ import (
 	"errors"
 	"sort"
 	"strconv"
+	"time"
 )
 
 // Verify verifies that the Proof contains a correct and un-broken chain of
@@ -13,11 +14,93 @@ import (
 // VerifiedReferences that specify what data should be looked for where to
 // complete the proof. If the Proof is not valid or the Proof does not contain
 // any references that can be used for the particular input data an error is
-// returned. If timestamp != 0 the references are also checked to include that.
+// returned. If timestamp != 0 the references are also checked to include that,
+// and references published before it are left out.
 func (p Proof) Verify(data []byte, timestamp int64) ([]VerifiedReference, error) {
-	if data == nil || len(data) <= 0 {
+	refs, err := p.VerifyAll(timestamp, data)
+	if err != nil {
+		return nil, err
+	}
+	return refs[0], nil
+}
+
+// VerifyOptions limits the resources used to verify a proof, so that a proof from
+// a hostile or buggy source cannot make verification run out of time or memory.
+// A limit of 0 means no limit.
+type VerifyOptions struct {
+	// Maximum number of operations in the proof
+	MaxOperations int
+	// Maximum size in bytes of each element of Proof.Data
+	MaxDataSize int
+	// Maximum number of bytes hashed or otherwise processed by all operations together
+	MaxHashedBytes int64
+	// Maximum number of references in the proof
+	MaxReferences int
+
+	// If not empty only the named hash functions are trusted. References depending
+	// on other hash functions are left out of the result.
+	Allow []string
+	// Hash functions that are not trusted even if registered
+	Deny []string
+}
+
+// denied returns the first of hashes that may not be used, or "".
+func (opts VerifyOptions) denied(hashes []string) string {
+	for _, h := range hashes {
+		if !opts.allowed(h) {
+			return h
+		}
+	}
+	return ""
+}
+
+// allowed reports if the hash function name may be used.
+func (opts VerifyOptions) allowed(name string) bool {
+	for _, d := range opts.Deny {
+		if d == name {
+			return false
+		}
+	}
+	if len(opts.Allow) == 0 {
+		return true
+	}
+	for _, a := range opts.Allow {
+		if a == name {
+			return true
+		}
+	}
+	return false
+}
+
+// DefaultVerifyOptions are used by Verify and VerifyAll. They are far above what
+// any proof from veriff.io needs.
+var DefaultVerifyOptions = VerifyOptions{
+	MaxOperations:  100000,
+	MaxDataSize:    1 << 20,
+	MaxHashedBytes: 64 << 20,
+	MaxReferences:  10000,
+}
+
+// VerifyAll works like Verify for several inputs at once, for example the sha2
+// and sha3 hashes of the same document, but only runs the operations once. The
+// references for inputs[i] are returned at index i. An error is returned if any
+// of the inputs is not proven by at least one reference.
+//
+// The time taken is linear in the size of the proof times the number of inputs.
+func (p Proof) VerifyAll(timestamp int64, inputs ...[]byte) ([][]VerifiedReference, error) {
+	return p.VerifyWith(DefaultVerifyOptions, timestamp, inputs...)
+}
+
+// VerifyWith works like VerifyAll but with the given limits.
+func (p Proof) VerifyWith(opts VerifyOptions, timestamp int64, inputs ...[]byte) ([][]VerifiedReference, error) {
+	if len(inputs) <= 0 {
 		return nil, errors.New("no data to verify")
 	}
+	for _, data := range inputs {
+		if data == nil || len(data) <= 0 {
+			return nil, errors.New("no data to verify")
+		}
+	}
 	if p.Data == nil || len(p.Data) <= 0 {
 		return nil, errors.New("no data in proof")
 	}
@@ -29,6 +112,24 @@ func (p Proof) Verify(data []byte, timestamp int64) ([]VerifiedReference, error)
 	if p.References == nil || len(p.References) <= 0 {
 		return nil, errors.New("no referene")
 	}
+	if opts.MaxOperations > 0 && len(p.Operations) > opts.MaxOperations {
+		return nil, errors.New("too many operations in proof")
+	}
+	if opts.MaxReferences > 0 && len(p.References) > opts.MaxReferences {
+		return nil, errors.New("too many references in proof")
+	}
+	if opts.MaxDataSize > 0 {
+		for i, v := range p.Data {
+			if len(v) > opts.MaxDataSize {
+				return nil, errors.New("data number " + strconv.Itoa(i) + " is too large")
+			}
+		}
+		for _, o := range p.Operations {
+			if len(o.Literal) > opts.MaxDataSize {
+				return nil, errors.New("literal of operation is too large")
+			}
+		}
+	}
 
 	// to verify the proof we do the following:
 	// 1. For each operation:
@@ -39,8 +140,12 @@ func (p Proof) Verify(data []byte, timestamp int64) ([]VerifiedReference, error)
 	// 2. For each reference:
 	//   a. Check that the data is in the produced data array
 	//   b. Check that the reference is not empty.
-	//   c. Check that the input data is a child of this reference.
-	//	 d. Also check that the timestep is included if provided
+	// 3. For each input, in one pass over the operations, find which outputs
+	//    depend on the input and the timestamp and the hashes used to get there.
+	// 4. For each reference:
+	//   a. Check that the input data is a child of this reference.
+	//	 b. Also check that the timestep is included if provided
+	//   c. Leave it out if it depends on a hash function that is not allowed
 
 	tdata := []byte{}
 	if timestamp != 0 {
@@ -50,12 +155,17 @@ func (p Proof) Verify(data []byte, timestamp int64) ([]VerifiedReference, error)
 
 	outData := [][]byte{}
 	inBuf := make([]byte, 0, 512/8*2)
+	hashed := int64(0)
 
 	for _, o := range p.Operations {
-		op := operations[o.Type]
-		if op == nil {
+		lop := literalOperations[o.Type]
+		op, ok := LookupOperation(o.Type)
+		if !ok && lop == nil {
 			return nil, errors.New("unknown operation '" + o.Type + "'")
 		}
+		if (lop == nil || o.Type == REVERSE) && len(o.Literal) > 0 {
+			return nil, errors.New("operation '" + o.Type + "' cannot have a literal")
+		}
 		if o.Data == nil || len(o.Data) <= 0 {
 			return nil, errors.New("each operation mush have an input")
 		}
@@ -72,8 +182,16 @@ func (p Proof) Verify(data []byte, timestamp int64) ([]VerifiedReference, error)
 			} else {
 				return nil, errors.New("refering to undefined data element " + strconv.Itoa(di))
 			}
+			if opts.MaxHashedBytes > 0 && hashed+int64(len(inBuf)+len(o.Literal)) > opts.MaxHashedBytes {
+				return nil, errors.New("proof hashes too much data")
+			}
+		}
+		hashed += int64(len(inBuf) + len(o.Literal))
+		if lop != nil {
+			outData = append(outData, lop(inBuf, o.Literal))
+		} else {
+			outData = append(outData, op.Func(inBuf))
 		}
-		outData = append(outData, op(inBuf))
 	}
 
 	refData := make([]VerifiedReference, len(p.References))
@@ -84,8 +202,9 @@ func (p Proof) Verify(data []byte, timestamp int64) ([]VerifiedReference, error)
 		if r.Data < 0 {
 			if -r.Data <= len(outData) {
 				refData[ri] = VerifiedReference{
-					data: outData[-r.Data-1],
-					ref:  r.Ref,
+					data:      outData[-r.Data-1],
+					ref:       r.Ref,
+					published: r.Timestamp,
 				}
 			} else {
 				return nil, errors.New("reference refers to non-existing data " + strconv.Itoa(r.Data))
@@ -97,59 +216,157 @@ func (p Proof) Verify(data []byte, timestamp int64) ([]VerifiedReference, error)
 		}
 	}
 
-	// now, for each of the verified references we have added we walk backwards to
-	// check if it is dependent on the data we were given, if it is not we remove it
-	// from our list since we cannot then trust it. Through this loop we will also collect
-	// information about the hashes which were used to calculate it.
+	// now, for each input, we keep the references that depend on it since only they
+	// can be trusted. The hash functions used on the way are collected as well.
 
-	refs := make([]VerifiedReference, 0, len(p.References))
-	for ri, r := range p.References {
-		if ok1, ok2, hashes := recData(p, data, tdata, r.Data); ok1 && (len(tdata) == 0 || ok2) {
-			sort.Strings(hashes)
-			refData[ri].hashes = hashes
-			refs = append(refs, refData[ri])
+	types := newTypeIndex(p.Operations)
+	res := make([][]VerifiedReference, len(inputs))
+	for ii, data := range inputs {
+		rs := p.reach(data, tdata, types)
+		refs := make([]VerifiedReference, 0, len(p.References))
+		// why the last reference depending on the input was left out
+		dropped := ""
+		for ri, r := range p.References {
+			if n := rs[-r.Data-1]; n.data && (len(tdata) == 0 || n.time) {
+				// the data cannot be published before it existed
+				if len(tdata) != 0 && !r.Timestamp.IsZero() && r.Timestamp.Before(time.Unix(0, timestamp)) {
+					dropped = "reference '" + r.Ref + "' is published before the timestamp"
+					continue
+				}
+				vr := refData[ri]
+				vr.hashes = types.names(n.hashes)
+				if h := opts.denied(vr.hashes); h != "" {
+					dropped = "operation '" + h + "' is not allowed"
+					continue
+				}
+				refs = append(refs, vr)
+			}
 		}
+		if len(refs) <= 0 && dropped != "" {
+			return nil, errors.New("the proof proves nothing for the input data, " + dropped)
+		}
+		if len(refs) <= 0 {
+			return nil, errors.New("the proof proves nothing for the input data")
+		}
+		res[ii] = refs
 	}
+	return res, nil
+}
 
-	if len(refs) <= 0 {
-		return nil, errors.New("the proof proves nothing for the input data")
-	}
-
-	return refs, nil
+// reachability tells if the output of an operation depends on the input data
+// and the timestamp, and the hash functions used on the paths from them.
+type reachability struct {
+	data, time bool
+	hashes     bitset
 }
 
-func recData(p Proof, data, tdata []byte, pos int) (bool, bool, []string) {
-	if pos >= 0 {
-		return bytes.Compare(data, p.Data[pos]) == 0, bytes.Compare(tdata, p.Data[pos]) == 0, []string{}
-	}
-	var o1, o2 bool
-	h := map[string]struct{}{}
-	for _, nPos := range p.Operations[-pos-1].Data {
-		if okd, okt, hashes := recData(p, data, tdata, nPos); okd || okt {
-			add := true
-			for _, v := range hashes {
-				if p.Operations[-pos-1].Type == v {
-					add = false
-					break
-				}
-			}
-			if add {
-				hashes = append(hashes, p.Operations[-pos-1].Type)
-			}
-			for _, hs := range hashes {
-				h[hs] = struct{}{}
+// reach computes the reachability of all operation outputs in one pass. The
+// operations must already have been checked to refer only to earlier outputs.
+func (p Proof) reach(data, tdata []byte, types typeIndex) []reachability {
+	dataHit := make([]reachability, len(p.Data))
+	for i, d := range p.Data {
+		dataHit[i].data = bytes.Equal(data, d)
+		dataHit[i].time = bytes.Equal(tdata, d)
+	}
+
+	out := make([]reachability, len(p.Operations))
+	for oi, o := range p.Operations {
+		var r reachability
+		for _, di := range o.Data {
+			var in *reachability
+			if di < 0 {
+				in = &out[-di-1]
+			} else {
+				in = &dataHit[di]
 			}
-			if okd {
-				o1 = true
+			if !in.data && !in.time {
+				continue
 			}
-			if okt {
-				o2 = true
+			r.data = r.data || in.data
+			r.time = r.time || in.time
+			r.hashes = r.hashes.or(in.hashes)
+		}
+		if i, ok := types.index[o.Type]; ok && (r.data || r.time) {
+			r.hashes = r.hashes.with(i)
+		}
+		out[oi] = r
+	}
+	return out
+}
+
+// typeIndex numbers the hash functions used in a proof.
+type typeIndex struct {
+	index map[string]int
+	types []string
+}
+
+func newTypeIndex(ops []Operation) typeIndex {
+	ti := typeIndex{index: map[string]int{}}
+	for _, o := range ops {
+		if _, ok := ti.index[o.Type]; !ok && literalOperations[o.Type] == nil {
+			ti.index[o.Type] = len(ti.types)
+			ti.types = append(ti.types, o.Type)
+		}
+	}
+	return ti
+}
+
+// names returns the sorted names of the types in b.
+func (ti typeIndex) names(b bitset) []string {
+	names := []string{}
+	for i, t := range ti.types {
+		if b.has(i) {
+			names = append(names, t)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// bitset is a set of small integers. Operations never modify a bitset in place
+// so that they can be shared between reachabilities.
+type bitset []uint64
+
+func (b bitset) has(i int) bool {
+	return i/64 < len(b) && b[i/64]&(1<<uint(i%64)) != 0
+}
+
+func (b bitset) with(i int) bitset {
+	if b.has(i) {
+		return b
+	}
+	n := len(b)
+	if i/64 >= n {
+		n = i/64 + 1
+	}
+	c := make(bitset, n)
+	copy(c, b)
+	c[i/64] |= 1 << uint(i%64)
+	return c
+}
+
+func (b bitset) or(o bitset) bitset {
+	if len(o) == 0 {
+		return b
+	}
+	if len(b) == 0 {
+		return o
+	}
+	if len(o) > len(b) {
+		b, o = o, b
+	}
+	var c bitset
+	for i, w := range o {
+		if b[i]|w != b[i] {
+			if c == nil {
+				c = make(bitset, len(b))
+				copy(c, b)
 			}
+			c[i] |= w
 		}
 	}
-	hashes := make([]string, 0, len(h))
-	for k := range h {
-		hashes = append(hashes, k)
+	if c == nil {
+		return b
 	}
-	return o1, o2, hashes
+	return c
 }
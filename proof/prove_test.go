@@ -0,0 +1,465 @@
+package proof
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// chainProof returns a proof of n operations, each hashing the previous output.
+func chainProof(n int) Proof {
+	p := Proof{Data: [][]byte{[]byte("input"), []byte("salt")}}
+	p.Operations = append(p.Operations, Operation{Type: SHA2_256, Data: []int{0, 1}})
+	for i := 1; i < n; i++ {
+		typ := SHA2_256
+		if i%2 == 0 {
+			typ = SHA3_512
+		}
+		p.Operations = append(p.Operations, Operation{Type: typ, Data: []int{-i}})
+	}
+	p.References = []Reference{{Data: -n, Ref: "end of chain"}}
+	return p
+}
+
+// diamondProof returns a proof of n operations where each one uses the output of
+// the previous one twice, giving 2^n paths from the input to the reference.
+func diamondProof(n int) Proof {
+	p := Proof{Data: [][]byte{[]byte("input")}}
+	p.Operations = append(p.Operations, Operation{Type: SHA2_256, Data: []int{0}})
+	for i := 1; i < n; i++ {
+		p.Operations = append(p.Operations, Operation{Type: SHA2_256, Data: []int{-i, -i, 0}})
+	}
+	p.References = []Reference{{Data: -n, Ref: "diamond"}}
+	return p
+}
+
+func TestVerifyHashFunctions(t *testing.T) {
+	// the sha3 operation only hashes the salt and must not count for the input
+	p := Proof{
+		Data: [][]byte{[]byte("input"), []byte("salt")},
+		Operations: []Operation{
+			{Type: SHA3_512, Data: []int{1}},
+			{Type: SHA2_256, Data: []int{0, -1}},
+		},
+		References: []Reference{{Data: -2, Ref: "a"}, {Data: -1, Ref: "b"}},
+	}
+	refs, err := p.VerifyAll(0, []byte("input"), []byte("salt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(refs[0]) != 1 || !reflect.DeepEqual(refs[0][0].HashFunctions(), []string{SHA2_256}) {
+		t.Error("unexpected references for input", refs[0])
+	}
+	if len(refs[1]) != 2 || !reflect.DeepEqual(refs[1][0].HashFunctions(), []string{SHA2_256, SHA3_512}) {
+		t.Error("unexpected references for salt", refs[1])
+	}
+	if _, err := p.Verify([]byte("other"), 0); err == nil {
+		t.Error("proof proved unrelated data")
+	}
+}
+
+func TestVerifyDiamond(t *testing.T) {
+	// would never finish if all paths were walked
+	refs, err := diamondProof(200).Verify([]byte("input"), 0)
+	if err != nil || len(refs) != 1 {
+		t.Fatal(refs, err)
+	}
+}
+
+func BenchmarkVerifyChain(b *testing.B) {
+	p := chainProof(10000)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := p.Verify([]byte("input"), 0); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkVerifyDiamond(b *testing.B) {
+	p := diamondProof(10000)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := p.Verify([]byte("input"), 0); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestVerifyLimits(t *testing.T) {
+	p := chainProof(100)
+	if _, err := p.VerifyWith(VerifyOptions{MaxOperations: 99}, 0, []byte("input")); err == nil {
+		t.Error("operation limit not enforced")
+	}
+	if _, err := p.VerifyWith(VerifyOptions{MaxDataSize: 4}, 0, []byte("input")); err == nil {
+		t.Error("data size limit not enforced")
+	}
+	if _, err := p.VerifyWith(VerifyOptions{MaxHashedBytes: 1000}, 0, []byte("input")); err == nil {
+		t.Error("hashed bytes limit not enforced")
+	}
+	if _, err := p.VerifyWith(VerifyOptions{MaxOperations: 100, MaxDataSize: 5, MaxHashedBytes: 10000}, 0, []byte("input")); err != nil {
+		t.Error("limits enforced too early:", err)
+	}
+}
+
+func FuzzVerify(f *testing.F) {
+	f.Add([]byte(`{"operations":[{"type":"sha3_512","data":[0]},{"type":"sha2_256","data":[-1,0]}],
+		"data":["AQIDBAUGBwgJCgsMDQ4PEBESExQ="],"references":[{"data":-2,"ref":"a"}]}`), int64(0))
+	f.Add([]byte(`{"operations":[{"type":"sha2_256","data":[0,1]}],"data":["AQ==","AAAAAAAAA+g="],
+		"references":[{"data":-1,"ref":"a"},{"data":0,"ref":"b"}]}`), int64(1000))
+	f.Fuzz(func(t *testing.T, js []byte, ts int64) {
+		var p Proof
+		if json.Unmarshal(js, &p) != nil || len(p.Data) == 0 {
+			return
+		}
+		opts := VerifyOptions{MaxOperations: 1000, MaxDataSize: 1000, MaxHashedBytes: 1 << 20, MaxReferences: 100}
+		refs, err := p.VerifyWith(opts, ts, p.Data[0])
+		if err != nil {
+			return
+		}
+		for _, r := range refs[0] {
+			if len(r.Data()) == 0 || r.Ref() == "" {
+				t.Error("bad verified reference", r)
+			}
+		}
+	})
+}
+
+func TestRegisterOperation(t *testing.T) {
+	rev := HashFunc{Name: "test_reverse_sha2_256", Size: 32, Func: func(in []byte) []byte {
+		out := opSha2_256(in)
+		for i, j := 0, len(out)-1; i < j; i, j = i+1, j-1 {
+			out[i], out[j] = out[j], out[i]
+		}
+		return out
+	}}
+	p := Proof{
+		Data:       [][]byte{[]byte("input")},
+		Operations: []Operation{{Type: rev.Name, Data: []int{0}}},
+		References: []Reference{{Data: -1, Ref: "a"}},
+	}
+	if _, err := p.Verify([]byte("input"), 0); err == nil {
+		t.Error("verified with unregistered operation")
+	}
+	if err := RegisterOperation(rev); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { unregisterOperation(rev.Name) })
+	if err := RegisterOperation(rev); err == nil {
+		t.Error("registered the same name twice")
+	}
+	if _, err := p.Verify([]byte("input"), 0); err != nil {
+		t.Error(err)
+	}
+	if _, err := p.VerifyWith(VerifyOptions{Deny: []string{rev.Name}}, 0, []byte("input")); err == nil {
+		t.Error("denied operation used")
+	}
+	if _, err := p.VerifyWith(VerifyOptions{Allow: []string{SHA2_256}}, 0, []byte("input")); err == nil {
+		t.Error("operation not in allow list used")
+	}
+
+	// only the references depending on an untrusted hash function are left out
+	var b Builder
+	in := b.AddData([]byte("input"))
+	b.Reference(b.Hash(SHA3_512, in), "sha3", time.Time{})
+	b.Reference(b.Hash(KECCAK_256, in), "keccak", time.Time{})
+	if p, err := b.Proof(); err != nil {
+		t.Fatal(err)
+	} else if refs, err := p.VerifyWith(VerifyOptions{Allow: []string{SHA3_512}}, 0, []byte("input")); err != nil || len(refs[0]) != 1 || refs[0][0].Ref() != "sha3" {
+		t.Error("unexpected references with allow list", refs, err)
+	}
+}
+
+// unregisterOperation undoes RegisterOperation so that tests can be repeated.
+func unregisterOperation(name string) {
+	opMu.Lock()
+	delete(operations, name)
+	opMu.Unlock()
+}
+
+func TestOperationVectors(t *testing.T) {
+	vectors := []struct {
+		op, in, out string
+	}{
+		{SHA2_256, "abc", "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad"},
+		{SHA2_512, "abc", "ddaf35a193617abacc417349ae20413112e6fa4e89a97ea20a9eeee64b55d39a2192992a274fc1a836ba3c23a3feebbd454d4423643ce80e2a9ac94fa54ca49f"},
+		{SHA3_256, "abc", "3a985da74fe225b2045c172d6bd390bd855f086e3e9d525b46bfe24511431532"},
+		{BLAKE2B_256, "abc", "bddd813c634239723171ef3fee98579b94964e3bb1cb3e427262c8c068d52319"},
+		{BLAKE2B_512, "abc", "ba80a53f981c4d0d6a2797b69f12f6e94c212f14685ac4b74b12bb6fdbffa2d17d87c5392aab792dc252d5de4533cc9518d38aa8dbf1925ab92386edd4009923"},
+		{KECCAK_256, "", "c5d2460186f7233c927e7db2dcc703c0e500b653ca82273b7bfad8045d85a470"},
+		{SHA2_256D, "", "5df6e0e2761359d30a8275058e299fcc0381534545f55cf43e41983f5d4c9456"},
+		{HASH160, "", "b472a266d0bd89c13706a4132ccfb16f7c3b9fcb"},
+	}
+	for _, v := range vectors {
+		h, ok := LookupOperation(v.op)
+		if !ok {
+			t.Error("operation not registered:", v.op)
+			continue
+		}
+		out := h.Func([]byte(v.in))
+		if hex.EncodeToString(out) != v.out {
+			t.Errorf("%s(%q) = %x, want %s", v.op, v.in, out, v.out)
+		}
+		if len(out) != h.Size {
+			t.Errorf("%s has size %d, registered as %d", v.op, len(out), h.Size)
+		}
+	}
+}
+
+func TestLiteralOperations(t *testing.T) {
+	// embed a hash, in Bitcoin byte order, in a made up block header
+	h := opSha2_256([]byte("input"))
+	p := Proof{
+		Data: [][]byte{h},
+		Operations: []Operation{
+			{Type: REVERSE, Data: []int{0}},
+			{Type: PREPEND, Data: []int{-1}, Literal: []byte("version+prev")},
+			{Type: APPEND, Data: []int{-2}, Literal: []byte("time+bits+nonce")},
+			{Type: SHA2_256D, Data: []int{-3}},
+			{Type: REVERSE, Data: []int{-4}},
+		},
+		References: []Reference{{Data: -5, Ref: "block"}},
+	}
+	refs, err := p.Verify(h, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rh := opReverse(h, nil)
+	header := []byte("version+prev" + string(rh) + "time+bits+nonce")
+	if !reflect.DeepEqual(refs[0].Data(), opReverse(opSha2_256d(header), nil)) {
+		t.Error("unexpected block hash", refs[0].DataBase64())
+	}
+	if !reflect.DeepEqual(refs[0].HashFunctions(), []string{SHA2_256D}) {
+		t.Error("unexpected hash functions", refs[0].HashFunctions())
+	}
+
+	p.Operations[0].Literal = []byte("x")
+	if _, err := p.Verify(h, 0); err == nil {
+		t.Error("reverse with literal accepted")
+	}
+}
+
+func TestBuilder(t *testing.T) {
+	var b Builder
+	in := b.AddData([]byte("input"))
+	salt := b.AddData([]byte("salt"))
+	if b.AddData([]byte("input")) != in {
+		t.Error("same data added twice")
+	}
+	n := b.Hash(SHA2_256, in, salt)
+	n = b.Reverse(n)
+	n = b.Hash(SHA3_512, b.Prepend([]byte("pre"), n))
+	b.Reference(n, "somewhere", time.Time{})
+	p, err := b.Proof()
+	if err != nil {
+		t.Fatal(err)
+	}
+	refs, err := p.Verify([]byte("input"), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(refs[0].HashFunctions(), []string{SHA2_256, SHA3_512}) {
+		t.Error("unexpected hash functions", refs[0].HashFunctions())
+	}
+
+	var bad Builder
+	bad.Hash("no such hash", bad.AddData([]byte("input")))
+	if _, err := bad.Proof(); err == nil {
+		t.Error("unknown operation accepted")
+	}
+	bad = Builder{}
+	bad.Reference(bad.AddData([]byte("input")), "data directly", time.Time{})
+	if _, err := bad.Proof(); err == nil {
+		t.Error("reference to data accepted")
+	}
+}
+
+func TestPrune(t *testing.T) {
+	// two documents chained into a common head, plus an unrelated branch
+	var b Builder
+	a := b.Hash(SHA2_256, b.AddData([]byte("document a")))
+	other := b.Hash(SHA3_512, b.AddData([]byte("unrelated")))
+	bb := b.Hash(SHA2_256, b.AddData([]byte("document b")))
+	head := b.Hash(SHA3_512, a, bb)
+	b.Reference(head, "head", time.Time{})
+	b.Reference(bb, "only b", time.Time{})
+	b.Reference(other, "unrelated", time.Time{})
+	p, err := b.Proof()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pp, err := p.Prune([]byte("document a"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pp.Data) != 2 || len(pp.Operations) != 3 || len(pp.References) != 1 {
+		t.Errorf("proof not minimal: %+v", pp)
+	}
+	refs, err := p.Verify([]byte("document a"), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	prefs, err := pp.Verify([]byte("document a"), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(refs, prefs) {
+		t.Error("pruned proof gives other references", refs, prefs)
+	}
+	if _, err := pp.Verify([]byte("document b"), 0); err != nil {
+		t.Error("document b should still be proven through the head:", err)
+	}
+	if _, err := p.Prune([]byte("not in proof")); err == nil {
+		t.Error("pruned for data not in proof")
+	}
+}
+
+func TestParseReference(t *testing.T) {
+	txid := "4a5e1e4baab89f3a32518a88c31bc87f618f76673e2cc77ab2127b7afdeda33b"
+	good := []struct {
+		ref, kind, source, id string
+		page                  int
+	}{
+		{"encyclopedia britannica", KindLegacy, "", "", 0},
+		{"Note: see page 3", KindLegacy, "", "", 0},
+		{"https://example.com/2017/hash.html", KindWeb, "example.com", "", 0},
+		{"https://EXAMPLE.com/2017/hash.html", KindWeb, "example.com", "", 0},
+		{"file:///archive/2017.txt", KindFile, "", "", 0},
+		{"bitcoin:tx/" + txid, KindBitcoinTx, "mainnet", txid, 0},
+		{"bitcoin:testnet/block/" + txid, KindBitcoinBlock, "testnet", txid, 0},
+		{"git:https://github.com/veriffio/client-go#7caee63", KindGitCommit, "https://github.com/veriffio/client-go", "7caee63", 0},
+		{"newspaper:The%20Times/2017-05-01/12", KindNewspaper, "The Times", "2017-05-01", 12},
+	}
+	for _, g := range good {
+		l, err := ParseReference(g.ref)
+		if err != nil {
+			t.Error(g.ref, err)
+			continue
+		}
+		if l.Kind != g.kind || l.Source != g.source || l.ID != g.id || l.Page != g.page || l.String() != g.ref {
+			t.Errorf("%s parsed as %+v", g.ref, l)
+		}
+	}
+	for _, bad := range []string{"https:///no-host", "bitcoin:tx/1234", "bitcoin:coin/" + txid, "git:repo#xyz", "newspaper:Times/May 1st"} {
+		if _, err := ParseReference(bad); err == nil {
+			t.Error("malformed reference accepted:", bad)
+		}
+	}
+}
+
+func TestTrustPolicy(t *testing.T) {
+	now := time.Unix(1500000000, 0)
+	var b Builder
+	n := b.Hash(SHA3_512, b.AddData([]byte("input")))
+	b.Reference(n, "https://example.com/a", now.Add(time.Hour))
+	b.Reference(n, "https://EXAMPLE.com/b", now.Add(time.Hour))
+	b.Reference(b.Hash(SHA2_256, n), "bitcoin:tx/4a5e1e4baab89f3a32518a88c31bc87f618f76673e2cc77ab2127b7afdeda33b", now.Add(48*time.Hour))
+	p, err := b.Proof()
+	if err != nil {
+		t.Fatal(err)
+	}
+	refs, err := p.Verify([]byte("input"), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a reference verified for two inputs is trusted if either path is
+	var mb Builder
+	head := mb.Hash(SHA3_512, mb.Hash(SHA2_256, mb.AddData([]byte("a"))), mb.Hash(SHA3_512, mb.AddData([]byte("b"))))
+	mb.Reference(head, "https://example.com/head", time.Time{})
+	mp, err := mb.Proof()
+	if err != nil {
+		t.Fatal(err)
+	}
+	all, err := mp.VerifyAll(0, []byte("a"), []byte("b"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	v := TrustPolicy{TrustedHashes: []string{SHA3_512}}.Evaluate(append(all[0], all[1]...), 0)
+	if !v.Accepted || len(v.Trusted) != 1 || len(v.Reasons) != 0 {
+		t.Errorf("trusted path not used: %+v", v)
+	}
+
+	tests := []struct {
+		tp       TrustPolicy
+		accepted bool
+		trusted  int
+	}{
+		{TrustPolicy{}, true, 3},
+		{TrustPolicy{MinReferences: 2}, true, 3},
+		{TrustPolicy{MinReferences: 3}, false, 3},
+		{TrustPolicy{TrustedHashes: []string{SHA3_512}}, true, 2},
+		{TrustPolicy{TrustedHashes: []string{SHA3_512}, MinReferences: 2}, false, 2},
+		{TrustPolicy{TrustedSources: []string{KindBitcoinTx}}, true, 1},
+		{TrustPolicy{TrustedSources: []string{"example.com"}}, true, 2},
+		{TrustPolicy{TrustedSources: []string{"example.org"}}, false, 0},
+		{TrustPolicy{MaxDelay: 24 * time.Hour}, true, 2},
+	}
+	for i, tt := range tests {
+		// duplicates must not count as independent references
+		v := tt.tp.Evaluate(append(refs, refs...), now.UnixNano())
+		if v.Accepted != tt.accepted || len(v.Trusted) != tt.trusted {
+			t.Errorf("%d: got %+v", i, v)
+		}
+		if !v.Accepted && len(v.Reasons) == 0 {
+			t.Errorf("%d: rejected without reason", i)
+		}
+	}
+}
+
+func TestReferenceTimestamp(t *testing.T) {
+	ts := time.Unix(1500000000, 0)
+	var b Builder
+	n := b.Hash(SHA3_512, b.AddData([]byte("input")), b.AddData(tsData(ts.UnixNano())))
+	b.Reference(n, "later", ts.Add(2*time.Hour))
+	b.Reference(n, "earlier", ts.Add(time.Hour))
+	b.Reference(n, "unknown", time.Time{})
+	p, err := b.Proof()
+	if err != nil {
+		t.Fatal(err)
+	}
+	refs, err := p.Verify([]byte("input"), ts.UnixNano())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !refs[0].Timestamp().Equal(ts.Add(2 * time.Hour)) {
+		t.Error("timestamp not carried through", refs[0].Timestamp())
+	}
+	if e, ok := EarliestTime(refs); !ok || !e.Equal(ts.Add(time.Hour)) {
+		t.Error("wrong earliest time", e, ok)
+	}
+	if _, ok := EarliestTime(refs[2:]); ok {
+		t.Error("earliest time without any publication time")
+	}
+
+	// a reference published before the timestamp is left out, and the proof fails
+	// if nothing else remains
+	p.References[0].Timestamp = ts.Add(-time.Second)
+	if refs, err := p.Verify([]byte("input"), ts.UnixNano()); err != nil || len(refs) != 2 {
+		t.Error("reference published before the timestamp not left out", refs, err)
+	}
+	p.References = p.References[:1]
+	if _, err := p.Verify([]byte("input"), ts.UnixNano()); err == nil {
+		t.Error("reference published before the timestamp accepted")
+	}
+
+	// an earlier reference for other data does not matter
+	b.Reference(b.Hash(SHA3_512, b.AddData([]byte("other"))), "old", ts.Add(-time.Hour))
+	if p, err = b.Proof(); err != nil {
+		t.Fatal(err)
+	}
+	if refs, err = p.Verify([]byte("input"), ts.UnixNano()); err != nil || len(refs) != 3 {
+		t.Error("unrelated earlier reference not ignored", refs, err)
+	}
+}
+
+// tsData returns the bytes a proof uses for a timestamp.
+func tsData(ns int64) []byte {
+	buf := make([]byte, 8)
+	binary.BigEndian.PutUint64(buf, uint64(ns))
+	return buf
+}
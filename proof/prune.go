@@ -0,0 +1,70 @@
+package proof
+
+// Prune returns the smallest proof with the same references for data as p. It
+// contains only the references that depend on data and the operations and data
+// they are calculated from, reindexed but in their original order. This is useful
+// to archive or share a proof from a server which also covers other items. An
+// error is returned if p does not verify for data.
+func (p Proof) Prune(data []byte) (Proof, error) {
+	if _, err := p.Verify(data, 0); err != nil {
+		return Proof{}, err
+	}
+	rs := p.reach(data, []byte{}, newTypeIndex(p.Operations))
+
+	// mark what is needed, walking backwards from the references
+	keepOp := make([]bool, len(p.Operations))
+	keepData := make([]bool, len(p.Data))
+	var refs []Reference
+	for _, r := range p.References {
+		if rs[-r.Data-1].data {
+			refs = append(refs, r)
+			keepOp[-r.Data-1] = true
+		}
+	}
+	for oi := len(p.Operations) - 1; oi >= 0; oi-- {
+		if !keepOp[oi] {
+			continue
+		}
+		for _, di := range p.Operations[oi].Data {
+			if di < 0 {
+				keepOp[-di-1] = true
+			} else {
+				keepData[di] = true
+			}
+		}
+	}
+
+	// reindex, keeping the order
+	var pp Proof
+	dataIndex := make([]int, len(p.Data))
+	for i, d := range p.Data {
+		if keepData[i] {
+			dataIndex[i] = len(pp.Data)
+			pp.Data = append(pp.Data, d)
+		}
+	}
+	opIndex := make([]int, len(p.Operations))
+	remap := func(di int) int {
+		if di < 0 {
+			return opIndex[-di-1]
+		}
+		return dataIndex[di]
+	}
+	for oi, o := range p.Operations {
+		if !keepOp[oi] {
+			continue
+		}
+		in := make([]int, len(o.Data))
+		for j, di := range o.Data {
+			in[j] = remap(di)
+		}
+		o.Data = in
+		pp.Operations = append(pp.Operations, o)
+		opIndex[oi] = -len(pp.Operations)
+	}
+	for _, r := range refs {
+		r.Data = remap(r.Data)
+		pp.References = append(pp.References, r)
+	}
+	return pp, nil
+}
@@ -0,0 +1,125 @@
+package proof
+
+import (
+	"strconv"
+	"time"
+)
+
+// A TrustPolicy describes which verified references a client is willing to
+// trust and how many of them are needed to accept a proof. The zero value
+// accepts any proof with at least one reference.
+type TrustPolicy struct {
+	// If not empty, every hash function used on the way to a reference must be
+	// in this list for the reference to be trusted.
+	TrustedHashes []string
+	// If not empty, a reference is only trusted if the kind or the source of its
+	// ReferenceLocator is in this list, e.g. KindBitcoinTx or "example.com".
+	TrustedSources []string
+	// Minimum number of trusted references from independent sources needed to
+	// accept the proof. References from the same web host, blockchain network,
+	// repository or newspaper are not independent. Values below 1 mean 1.
+	MinReferences int
+	// If not 0, references published longer than this after the timestamp of the
+	// item, or without a publication time, are not trusted.
+	MaxDelay time.Duration
+}
+
+// A Verdict is the outcome of evaluating verified references with a TrustPolicy.
+type Verdict struct {
+	Accepted bool
+	// The references that are trusted
+	Trusted []VerifiedReference
+	// Why references, or the whole proof, were rejected
+	Reasons []string
+}
+
+// Evaluate applies the policy to the references from Verify, or from client.Prove,
+// for an item with the given timestamp in nanoseconds since the unix epoch.
+func (tp TrustPolicy) Evaluate(refs []VerifiedReference, timestamp int64) Verdict {
+	var v Verdict
+	trusted := map[string]bool{}
+	reasons := map[string]string{}
+	var rejected []string
+	sources := map[string]bool{}
+	for _, vr := range refs {
+		// the same reference is often verified for more than one input, through
+		// different hash functions, and is trusted if any of those is
+		key := vr.Key()
+		if trusted[key] {
+			continue
+		}
+		if reason := tp.reject(vr, timestamp); reason != "" {
+			if _, ok := reasons[key]; !ok {
+				reasons[key] = "reference '" + vr.ref + "' " + reason
+				rejected = append(rejected, key)
+			}
+			continue
+		}
+		trusted[key] = true
+		v.Trusted = append(v.Trusted, vr)
+		l, _ := vr.Locator()
+		sources[independence(l)] = true
+	}
+	for _, key := range rejected {
+		if !trusted[key] {
+			v.Reasons = append(v.Reasons, reasons[key])
+		}
+	}
+
+	min := tp.MinReferences
+	if min < 1 {
+		min = 1
+	}
+	if len(sources) < min {
+		v.Reasons = append(v.Reasons, "trusted references from "+strconv.Itoa(len(sources))+" independent sources, need "+strconv.Itoa(min))
+		return v
+	}
+	v.Accepted = true
+	return v
+}
+
+// reject returns why vr is not trusted or "" if it is.
+func (tp TrustPolicy) reject(vr VerifiedReference, timestamp int64) string {
+	for _, h := range vr.hashes {
+		if len(tp.TrustedHashes) > 0 && !contains(tp.TrustedHashes, h) {
+			return "depends on untrusted hash function " + h
+		}
+	}
+	l, err := vr.Locator()
+	if err != nil {
+		return "is invalid: " + err.Error()
+	}
+	if len(tp.TrustedSources) > 0 && !contains(tp.TrustedSources, l.Kind) && !contains(tp.TrustedSources, l.Source) {
+		return "is not from a trusted source"
+	}
+	if tp.MaxDelay > 0 {
+		if vr.published.IsZero() {
+			return "has no publication time"
+		}
+		if vr.published.Sub(time.Unix(0, timestamp)) > tp.MaxDelay {
+			return "was published too late"
+		}
+	}
+	return ""
+}
+
+// independence returns a key that is equal for references that are not
+// independent of each other.
+func independence(l ReferenceLocator) string {
+	switch l.Kind {
+	case KindBitcoinTx, KindBitcoinBlock:
+		return "bitcoin:" + l.Source
+	case KindWeb, KindGitCommit, KindNewspaper:
+		return l.Kind + ":" + l.Source
+	}
+	return l.Kind + ":" + l.Raw
+}
+
+func contains(list []string, s string) bool {
+	for _, e := range list {
+		if e == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,160 @@
+package refcheck
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/veriffio/client-go/proof"
+)
+
+// DefaultMaxBody is the number of bytes read from a reference when no other
+// limit is given.
+const DefaultMaxBody = 10 << 20
+
+// HTTPChecker checks references that are http or https permalinks by fetching
+// them and searching the body for the data.
+type HTTPChecker struct {
+	// Client used for the requests, http.DefaultClient if nil
+	Client *http.Client
+	// Maximum number of bytes read from each reference, DefaultMaxBody if 0
+	MaxBody int64
+}
+
+// Check implements ReferenceChecker.
+func (hc HTTPChecker) Check(ctx context.Context, vr proof.VerifiedReference) error {
+	l, err := vr.Locator()
+	if err != nil || l.Kind != proof.KindWeb {
+		return ErrUnsupported
+	}
+	u := l.URL
+	req, err := http.NewRequestWithContext(ctx, "GET", u.String(), nil)
+	if err != nil {
+		return err
+	}
+	c := hc.Client
+	if c == nil {
+		c = http.DefaultClient
+	}
+	re, err := c.Do(req)
+	if err != nil {
+		return err
+	}
+	defer re.Body.Close()
+	if re.StatusCode != http.StatusOK {
+		return errors.New("unexpected response code " + strconv.Itoa(re.StatusCode) + " " + u.String())
+	}
+	return search(re.Body, hc.MaxBody, vr.Data())
+}
+
+// FileChecker checks references that are file URLs, such as file:///archive/2017.txt,
+// by searching the file for the data.
+type FileChecker struct {
+	// If not empty the paths of the references are relative to Root and may not
+	// refer to anything outside it.
+	Root string
+	// Maximum number of bytes read from each file, DefaultMaxBody if 0
+	MaxBody int64
+}
+
+// Check implements ReferenceChecker.
+func (fc FileChecker) Check(ctx context.Context, vr proof.VerifiedReference) error {
+	l, err := vr.Locator()
+	if err != nil || l.Kind != proof.KindFile {
+		return ErrUnsupported
+	}
+	p := filepath.FromSlash(l.URL.Path)
+	if fc.Root != "" {
+		p = filepath.Join(fc.Root, p)
+		if rel, err := filepath.Rel(fc.Root, p); err != nil || strings.HasPrefix(rel, "..") {
+			return ErrUnsupported
+		}
+	}
+	return searchFile(p, fc.MaxBody, vr.Data())
+}
+
+// ArchiveChecker checks any reference against a local copy of its content,
+// stored in Dir in a file named by the url.PathEscape encoded reference. This
+// allows checking references saved earlier or fetched by other means.
+type ArchiveChecker struct {
+	Dir string
+	// Maximum number of bytes read from each file, DefaultMaxBody if 0
+	MaxBody int64
+}
+
+// Check implements ReferenceChecker. A reference without a copy in the archive,
+// or that cannot be a file name like "..", is unsupported.
+func (ac ArchiveChecker) Check(ctx context.Context, vr proof.VerifiedReference) error {
+	name := url.PathEscape(vr.Ref())
+	if name == "" || name == "." || name == ".." {
+		return ErrUnsupported
+	}
+	err := searchFile(filepath.Join(ac.Dir, name), ac.MaxBody, vr.Data())
+	if os.IsNotExist(err) {
+		return ErrUnsupported
+	}
+	return err
+}
+
+// ByKind is a ReferenceChecker dispatching each reference to the checker for its
+// kind, as given by proof.ParseReference. Malformed references and kinds without
+// a checker are unsupported.
+type ByKind map[string]ReferenceChecker
+
+// Check implements ReferenceChecker.
+func (bk ByKind) Check(ctx context.Context, vr proof.VerifiedReference) error {
+	l, err := vr.Locator()
+	if err != nil {
+		return ErrUnsupported
+	}
+	c := bk[l.Kind]
+	if c == nil {
+		return ErrUnsupported
+	}
+	return c.Check(ctx, vr)
+}
+
+// Multi is a ReferenceChecker trying each checker in turn until one of them
+// supports the reference.
+type Multi []ReferenceChecker
+
+// Check implements ReferenceChecker.
+func (m Multi) Check(ctx context.Context, vr proof.VerifiedReference) error {
+	for _, c := range m {
+		if err := c.Check(ctx, vr); err != ErrUnsupported {
+			return err
+		}
+	}
+	return ErrUnsupported
+}
+
+func searchFile(name string, max int64, data []byte) error {
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return search(f, max, data)
+}
+
+// search reads at most max bytes from r and looks for data.
+func search(r io.Reader, max int64, data []byte) error {
+	if max <= 0 {
+		max = DefaultMaxBody
+	}
+	content, err := ioutil.ReadAll(io.LimitReader(r, max))
+	if err != nil {
+		return err
+	}
+	if !Contains(content, data) {
+		return ErrNotConfirmed
+	}
+	return nil
+}
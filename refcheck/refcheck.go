@@ -0,0 +1,78 @@
+// Package refcheck confirms that the references of a proof contain the proven data
+/*
+The proof package only verifies the chain of hash functions from the input data
+to the data of each reference, it does not check that the data really is
+published at the reference. A ReferenceChecker does that for some kinds of
+references and CheckReferences applies one to the output of proof.Proof.Verify.
+*/
+package refcheck
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+
+	"github.com/veriffio/client-go/proof"
+)
+
+var (
+	// ErrNotConfirmed is returned when the data was not found at the reference.
+	ErrNotConfirmed = errors.New("the data was not found at the reference")
+	// ErrUnsupported is returned by a checker that cannot check the reference.
+	ErrUnsupported = errors.New("the reference is not supported by the checker")
+)
+
+// A ReferenceChecker confirms that the data of a verified reference is published
+// at the reference. Check returns nil if it is, ErrNotConfirmed if it is not,
+// ErrUnsupported if the checker does not handle the reference and any other error
+// if the check could not be completed.
+type ReferenceChecker interface {
+	Check(ctx context.Context, vr proof.VerifiedReference) error
+}
+
+// A Result is the outcome of checking one reference.
+type Result struct {
+	Reference proof.VerifiedReference
+	Confirmed bool
+	Err       error
+}
+
+// CheckReferences checks each of the references with c, in order, and returns
+// one Result per reference.
+func CheckReferences(ctx context.Context, refs []proof.VerifiedReference, c ReferenceChecker) []Result {
+	res := make([]Result, len(refs))
+	for i, vr := range refs {
+		err := c.Check(ctx, vr)
+		res[i] = Result{Reference: vr, Confirmed: err == nil, Err: err}
+	}
+	return res
+}
+
+// Confirmed returns the references that were confirmed.
+func Confirmed(res []Result) []proof.VerifiedReference {
+	var refs []proof.VerifiedReference
+	for _, r := range res {
+		if r.Confirmed {
+			refs = append(refs, r.Reference)
+		}
+	}
+	return refs
+}
+
+// Contains reports if content contains data in any of the encodings commonly
+// used when publishing hashes: raw, hex (any case) or base64.
+func Contains(content, data []byte) bool {
+	if len(data) == 0 {
+		return false
+	}
+	if bytes.Contains(content, data) {
+		return true
+	}
+	if bytes.Contains(bytes.ToLower(content), []byte(hex.EncodeToString(data))) {
+		return true
+	}
+	return bytes.Contains(content, []byte(base64.StdEncoding.EncodeToString(data))) ||
+		bytes.Contains(content, []byte(base64.RawURLEncoding.EncodeToString(data)))
+}
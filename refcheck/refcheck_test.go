@@ -0,0 +1,110 @@
+package refcheck
+
+import (
+	"context"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/veriffio/client-go/proof"
+)
+
+func TestCheckReferences(t *testing.T) {
+	pages := map[string]string{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		page, ok := pages[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(page))
+	}))
+	defer srv.Close()
+	dir := t.TempDir()
+
+	var b proof.Builder
+	n := b.Hash(proof.SHA2_256, b.AddData([]byte("input")))
+	for _, ref := range []string{
+		srv.URL + "/published",
+		srv.URL + "/other",
+		srv.URL + "/missing",
+		"file:///archive.txt",
+		"file:///../escape.txt",
+		"the daily news",
+	} {
+		b.Reference(n, ref, time.Time{})
+	}
+	p, err := b.Proof()
+	if err != nil {
+		t.Fatal(err)
+	}
+	refs, err := p.Verify([]byte("input"), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := hex.EncodeToString(refs[0].Data())
+	pages["/published"] = "<p>today's hash is " + h + "</p>"
+	pages["/other"] = "<p>nothing here</p>"
+	if err := ioutil.WriteFile(filepath.Join(dir, "archive.txt"), []byte(h), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	c := Multi{HTTPChecker{Client: srv.Client()}, FileChecker{Root: dir}}
+	res := CheckReferences(context.Background(), refs, c)
+	expConfirmed := []bool{true, false, false, true, false, false}
+	for i, r := range res {
+		if r.Confirmed != expConfirmed[i] {
+			t.Error(r.Reference.Ref(), "confirmed:", r.Confirmed, r.Err)
+		}
+	}
+	if res[1].Err != ErrNotConfirmed || res[4].Err != ErrUnsupported || res[5].Err != ErrUnsupported {
+		t.Error("unexpected errors", res[1].Err, res[4].Err, res[5].Err)
+	}
+	if len(Confirmed(res)) != 2 {
+		t.Error("expected 2 confirmed references")
+	}
+
+	bk := ByKind{proof.KindWeb: HTTPChecker{Client: srv.Client()}}
+	if err := bk.Check(context.Background(), refs[0]); err != nil {
+		t.Error("web reference not dispatched:", err)
+	}
+	if err := bk.Check(context.Background(), refs[3]); err != ErrUnsupported {
+		t.Error("file reference without checker not unsupported:", err)
+	}
+}
+
+func TestArchiveChecker(t *testing.T) {
+	dir := t.TempDir()
+	var b proof.Builder
+	n := b.Hash(proof.SHA2_256, b.AddData([]byte("input")))
+	for _, ref := range []string{"the daily news", "https://example.com/a?b=c", "bitcoin:tx/missing", "..", "."} {
+		b.Reference(n, ref, time.Time{})
+	}
+	p, err := b.Proof()
+	if err != nil {
+		t.Fatal(err)
+	}
+	refs, err := p.Verify([]byte("input"), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := hex.EncodeToString(refs[0].Data())
+	if err := ioutil.WriteFile(filepath.Join(dir, "the%20daily%20news"), []byte("page 3: "+h), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "https:%2F%2Fexample.com%2Fa%3Fb=c"), []byte("nothing here"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	ac := ArchiveChecker{Dir: dir}
+	exp := []error{nil, ErrNotConfirmed, ErrUnsupported, ErrUnsupported, ErrUnsupported}
+	for i, vr := range refs {
+		if err := ac.Check(context.Background(), vr); err != exp[i] {
+			t.Error(vr.Ref(), "got", err, "expected", exp[i])
+		}
+	}
+}
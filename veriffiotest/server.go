@@ -0,0 +1,353 @@
+// Package veriffiotest implements the veriff.io web api in process for testing
+/*
+The Server keeps a real hash chain of all added items and hands out proofs that
+verify with proof.Proof.Verify. Added items are chained until Publish is called,
+which publishes the head of the chain at a made up reference and makes all items
+in the chain provable. Failures may be injected for any path with Fail.
+
+A Server is typically plugged into a client:
+
+	srv := veriffiotest.NewServer()
+	c := client.New("", client.WithTestHandler(srv))
+
+It may also be served over the network with net/http/httptest.
+*/
+package veriffiotest
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"net/http"
+	"path"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/veriffio/client-go/proof"
+	"github.com/veriffio/client-go/webapi"
+	"golang.org/x/crypto/sha3"
+)
+
+// DefaultHistoryLimit is the page size of history responses when the request
+// does not specify one.
+const DefaultHistoryLimit = 100
+
+// A Server is a http.Handler implementing the veriff.io web api. It is safe for
+// concurrent use.
+type Server struct {
+	mu           sync.Mutex
+	now          func() time.Time
+	entries      []entry
+	publications []publication
+	autoPublish  bool
+	failures     map[string][]int
+}
+
+// entry is one item in the chain.
+type entry struct {
+	ts     int64
+	sha2   []byte
+	sha3   []byte
+	token  []byte
+	tdata  []byte
+	link   []byte
+	linkIn []byte
+}
+
+// publication is a publication of the head of the chain at an external source.
+type publication struct {
+	head int
+	ref  string
+	time time.Time
+}
+
+// NewServer creates a server with an empty chain.
+func NewServer() *Server {
+	return &Server{
+		now:      time.Now,
+		failures: map[string][]int{},
+	}
+}
+
+// SetClock replaces the source of the current time, which is time.Now by default.
+func (s *Server) SetClock(now func() time.Time) {
+	s.mu.Lock()
+	s.now = now
+	s.mu.Unlock()
+}
+
+// SetAutoPublish makes the server publish the chain after every add, so that
+// items become provable at once.
+func (s *Server) SetAutoPublish(on bool) {
+	s.mu.Lock()
+	s.autoPublish = on
+	s.mu.Unlock()
+}
+
+// Publish publishes the current head of the chain, making all items added so far
+// provable, and returns the reference of the publication. Nothing is published
+// and "" is returned if there are no new items.
+func (s *Server) Publish() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.publish()
+}
+
+func (s *Server) publish() string {
+	head := len(s.entries) - 1
+	if head < 0 || len(s.publications) > 0 && s.publications[len(s.publications)-1].head == head {
+		return ""
+	}
+	// the timestamp of the head may be ahead of the clock, see add
+	t := s.now()
+	if ht := time.Unix(0, s.entries[head].ts); t.Before(ht) {
+		t = ht
+	}
+	p := publication{
+		head: head,
+		ref:  "veriffiotest:publication/" + strconv.Itoa(len(s.publications)),
+		time: t.UTC(),
+	}
+	s.publications = append(s.publications, p)
+	return p.ref
+}
+
+// Published returns the data that would be found at the reference returned by
+// Publish, or nil if there is no such publication.
+func (s *Server) Published(ref string) []byte {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, p := range s.publications {
+		if p.ref == ref {
+			return s.entries[p.head].link
+		}
+	}
+	return nil
+}
+
+// Fail makes the next n requests to the endpoint pth, one of the webapi Path
+// constants, fail with the http status code.
+func (s *Server) Fail(pth string, status int, n int) {
+	s.mu.Lock()
+	for i := 0; i < n; i++ {
+		s.failures[pth] = append(s.failures[pth], status)
+	}
+	s.mu.Unlock()
+}
+
+// ServeHTTP implements http.Handler.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	pth := path.Base(r.URL.Path)
+	if f := s.failures[pth]; len(f) > 0 {
+		s.failures[pth] = f[1:]
+		if f[0] == http.StatusBadRequest {
+			writeError(w, "injected failure")
+		} else {
+			w.WriteHeader(f[0])
+		}
+		return
+	}
+
+	switch pth {
+	case webapi.PathAdd:
+		var ar webapi.AddRequest
+		if !decode(w, r, &ar) {
+			return
+		}
+		writeJSON(w, s.add(ar))
+	case webapi.PathProve:
+		var pr webapi.ProveRequest
+		if !decode(w, r, &pr) {
+			return
+		}
+		resp, ok := s.prove(pr)
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		writeJSON(w, resp)
+	case webapi.PathLatest:
+		if len(s.entries) == 0 {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		e := s.entries[len(s.entries)-1]
+		writeJSON(w, webapi.LatestResponse{
+			Timestamp: strconv.FormatInt(e.ts, 10),
+			Sha2_256:  sum2(e.link),
+			Sha3_512:  e.link,
+		})
+	case webapi.PathFixpoints:
+		fps := webapi.FixpointsResponse{Points: []webapi.Fixpoint{}}
+		for _, p := range s.publications {
+			e := s.entries[p.head]
+			fps.Points = append(fps.Points, webapi.Fixpoint{
+				Timestamp: strconv.FormatInt(e.ts, 10),
+				Sha2_256:  sum2(e.link),
+				Sha3_512:  e.link,
+			})
+		}
+		writeJSON(w, fps)
+	case webapi.PathHistory:
+		var hr webapi.HistoryRequest
+		if !decode(w, r, &hr) {
+			return
+		}
+		writeJSON(w, s.history(hr))
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func (s *Server) add(ar webapi.AddRequest) webapi.AddResponse {
+	ts := s.now().UnixNano()
+	if n := len(s.entries); n > 0 && ts <= s.entries[n-1].ts {
+		ts = s.entries[n-1].ts + 1
+	}
+	e := entry{
+		ts:    ts,
+		sha2:  ar.Sha2_256,
+		sha3:  ar.Sha3_512,
+		token: make([]byte, 16),
+		tdata: make([]byte, 8),
+	}
+	rand.Read(e.token)
+	binary.BigEndian.PutUint64(e.tdata, uint64(ts))
+
+	// each link of the chain is the hash of the previous link and the new item
+	e.linkIn = append(append(append([]byte{}, e.tdata...), e.sha2...), e.sha3...)
+	var prev []byte
+	if n := len(s.entries); n > 0 {
+		prev = s.entries[n-1].link
+	}
+	e.link = sum3(append(append([]byte{}, prev...), e.linkIn...))
+	s.entries = append(s.entries, e)
+
+	if s.autoPublish {
+		s.publish()
+	}
+	return webapi.AddResponse{
+		Token:                e.token,
+		ApproximateTimestamp: strconv.FormatInt(ts, 10),
+		Sha2_256:             e.sha2,
+		Sha3_512:             e.sha3,
+	}
+}
+
+func (s *Server) prove(pr webapi.ProveRequest) (webapi.ProveResponse, bool) {
+	i := -1
+	for j, e := range s.entries {
+		if string(e.token) == string(pr.Token) && string(e.sha2) == string(pr.Sha2_256) {
+			i = j
+			break
+		}
+	}
+	if i < 0 {
+		return webapi.ProveResponse{}, false
+	}
+	e := s.entries[i]
+	resp := webapi.ProveResponse{
+		Timestamp: strconv.FormatInt(e.ts, 10),
+		Sha2_256:  e.sha2,
+		Sha3_512:  e.sha3,
+		Status:    webapi.StatusInChain,
+	}
+	for _, p := range s.publications {
+		if p.head >= i {
+			resp.Status = webapi.StatusProvable
+			resp.Proof = s.chainProof(i, p)
+			break
+		}
+	}
+	return resp, true
+}
+
+// chainProof returns a proof from entry i along the chain to the published head.
+func (s *Server) chainProof(i int, pub publication) proof.Proof {
+	var b proof.Builder
+	e := s.entries[i]
+	in := []proof.Node{b.AddData(e.tdata), b.AddData(e.sha2), b.AddData(e.sha3)}
+	if i > 0 {
+		in = append([]proof.Node{b.AddData(s.entries[i-1].link)}, in...)
+	}
+	n := b.Hash(proof.SHA3_512, in...)
+	for j := i + 1; j <= pub.head; j++ {
+		n = b.Hash(proof.SHA3_512, n, b.AddData(s.entries[j].linkIn))
+	}
+	b.Reference(n, pub.ref, pub.time)
+	p, err := b.Proof()
+	if err != nil {
+		panic("veriffiotest: " + err.Error())
+	}
+	return p
+}
+
+func (s *Server) history(hr webapi.HistoryRequest) webapi.HistoryResponse {
+	from, _ := strconv.ParseInt(hr.From, 10, 64)
+	to := int64(-1)
+	if hr.To != "" {
+		to, _ = strconv.ParseInt(hr.To, 10, 64)
+	}
+	limit := hr.Limit
+	if limit == 0 {
+		limit = DefaultHistoryLimit
+	}
+
+	resp := webapi.HistoryResponse{Entries: []webapi.HistoryEntry{}}
+	for _, e := range s.entries {
+		if e.ts < from || to >= 0 && e.ts >= to {
+			continue
+		}
+		if len(resp.Entries) == limit {
+			resp.Next = strconv.FormatInt(e.ts, 10)
+			break
+		}
+		resp.Entries = append(resp.Entries, webapi.HistoryEntry{
+			Timestamp: strconv.FormatInt(e.ts, 10),
+			Sha2_256:  sum2(e.link),
+			Sha3_512:  e.link,
+		})
+	}
+	return resp
+}
+
+// decode decodes and validates a request, writing a 400 response if it is bad.
+func decode(w http.ResponseWriter, r *http.Request, v interface{ Validate() error }) bool {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		writeError(w, err.Error())
+		return false
+	}
+	if err := v.Validate(); err != nil {
+		writeError(w, err.Error())
+		return false
+	}
+	return true
+}
+
+func writeError(w http.ResponseWriter, msg string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusBadRequest)
+	json.NewEncoder(w).Encode(struct {
+		Error string `json:"error"`
+	}{msg})
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+func sum2(b []byte) []byte {
+	s := sha256.Sum256(b)
+	return s[:]
+}
+
+func sum3(b []byte) []byte {
+	s := sha3.Sum512(b)
+	return s[:]
+}
@@ -0,0 +1,142 @@
+package veriffiotest_test
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/veriffio/client-go/client"
+	"github.com/veriffio/client-go/veriffiotest"
+	"github.com/veriffio/client-go/webapi"
+)
+
+func TestAddProve(t *testing.T) {
+	srv := veriffiotest.NewServer()
+	c := client.New("", client.WithTestHandler(srv))
+
+	var receipts []client.Receipt
+	for i := 0; i < 3; i++ {
+		rc, err := c.AddSlice([]byte("document " + strconv.Itoa(i)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		receipts = append(receipts, rc)
+	}
+	if _, err := c.ProveSlice([]byte("document 0"), receipts[0].Token); err != client.ErrStatusInChain {
+		t.Error("expected ErrStatusInChain, got", err)
+	}
+	if _, err := c.ProveSlice([]byte("document 0"), receipts[1].Token); err != client.ErrStatusNotFound {
+		t.Error("expected ErrStatusNotFound, got", err)
+	}
+
+	ref := srv.Publish()
+	for i, rc := range receipts {
+		res, err := c.ProveSlice([]byte("document "+strconv.Itoa(i)), rc.Token)
+		if err != nil {
+			t.Fatal(i, err)
+		}
+		if res.Timestamp != rc.ApproximateTime.UnixNano() {
+			t.Error("timestamp differs from receipt", res.Timestamp, rc.ApproximateTime)
+		}
+		for _, ar := range res.References {
+			r := ar.Reference()
+			if r.Ref() != ref || !bytes.Equal(r.Data(), srv.Published(ref)) {
+				t.Error("unexpected reference", r.Ref(), r.DataBase64())
+			}
+			// the chain only uses sha3_512
+			if ar.Anchor() != client.AnchorSha3 {
+				t.Error("unexpected anchor", ar.Anchor())
+			}
+		}
+	}
+
+	fps, err := c.Fixpoints()
+	if err != nil || len(fps) != 1 {
+		t.Fatal("unexpected fixpoints", fps, err)
+	}
+	_, s3, _, err := c.Latest()
+	if err != nil || !bytes.Equal(s3, fps[0].Sha3_512) {
+		t.Error("latest does not match fixpoint", err)
+	}
+}
+
+func TestHistory(t *testing.T) {
+	srv := veriffiotest.NewServer()
+	now := time.Unix(1000, 0)
+	srv.SetClock(func() time.Time {
+		now = now.Add(time.Second)
+		return now
+	})
+	c := client.New("", client.WithTestHandler(srv))
+	for i := 0; i < veriffiotest.DefaultHistoryLimit+10; i++ {
+		if _, err := c.AddSlice([]byte("document " + strconv.Itoa(i))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	h, err := c.History(time.Unix(1005, 0), time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(h) != veriffiotest.DefaultHistoryLimit+6 {
+		t.Error("unexpected number of history entries", len(h))
+	}
+	h, err = c.History(time.Unix(1005, 0), time.Unix(1010, 0))
+	if err != nil || len(h) != 5 {
+		t.Error("unexpected history", len(h), err)
+	}
+}
+
+func TestFailures(t *testing.T) {
+	srv := veriffiotest.NewServer()
+	srv.SetAutoPublish(true)
+	// also check that the server works over the network
+	hs := httptest.NewServer(srv)
+	defer hs.Close()
+	c := client.New(hs.URL, client.WithRetry(client.RetryPolicy{MaxAttempts: 2, BaseDelay: time.Millisecond}))
+
+	srv.Fail(webapi.PathAdd, http.StatusServiceUnavailable, 1)
+	rc, err := c.AddSlice([]byte("data"))
+	if err != nil {
+		t.Fatal("add was not retried:", err)
+	}
+
+	srv.Fail(webapi.PathProve, http.StatusBadRequest, 1)
+	if _, err := c.ProveSlice([]byte("data"), rc.Token); err == nil || err.Error() != "400:injected failure" {
+		t.Error("expected injected 400, got", err)
+	}
+	srv.Fail(webapi.PathProve, http.StatusNotFound, 1)
+	if _, err := c.ProveSlice([]byte("data"), rc.Token); err != client.ErrStatusNotFound {
+		t.Error("expected injected 404, got", err)
+	}
+	res, err := c.WaitProvable(context.Background(), bytes.NewReader([]byte("data")), rc.Token, client.PollPolicy{Interval: time.Millisecond})
+	if err != nil || len(res.References) == 0 {
+		t.Error("auto published item not provable", err)
+	}
+}
+
+func TestFixedClock(t *testing.T) {
+	srv := veriffiotest.NewServer()
+	fixed := time.Unix(1500000000, 0)
+	srv.SetClock(func() time.Time { return fixed })
+	c := client.New("", client.WithTestHandler(srv))
+
+	// the second item gets a timestamp after the clock, and so must the publication
+	var receipts []client.Receipt
+	for _, d := range []string{"a", "b"} {
+		rc, err := c.AddSlice([]byte(d))
+		if err != nil {
+			t.Fatal(err)
+		}
+		receipts = append(receipts, rc)
+	}
+	srv.Publish()
+	for i, d := range []string{"a", "b"} {
+		if _, err := c.ProveSlice([]byte(d), receipts[i].Token); err != nil {
+			t.Error(d, err)
+		}
+	}
+}
@@ -7,12 +7,16 @@ This package will typically not be used directly
 
 import (
 	"errors"
+	"strconv"
 
 	"github.com/veriffio/client-go/proof"
 )
 
 // Each item can been in one of three different states as described by these constants.
 const (
+	// The item is not known by the server, either because it has not yet been handled or
+	// because the token or hash is wrong. This status is signaled with a 404 response.
+	StatusNotFound = "notfound"
 	// The item has been stored by the server but there are as of yet no external references published.
 	StatusInChain = "chained"
 	// The item is stored in the chain and have references publised at external sources.
@@ -105,3 +109,48 @@ type Fixpoint struct {
 	Sha2_256  []byte `json:"sha2_256"`
 	Sha3_512  []byte `json:"sha3_512"`
 }
+
+// A HistoryRequest asks for the states of the chain with a timestamp in the range
+// [From, To). Timestamps are given as in the responses, an empty To means up to
+// the latest state. At most Limit entries are returned, 0 lets the server decide.
+type HistoryRequest struct {
+	From  string `json:"from"`
+	To    string `json:"to,omitempty"`
+	Limit int    `json:"limit,omitempty"`
+}
+
+// Validate performs sanity checks on the request.
+func (hr HistoryRequest) Validate() error {
+	from, err := strconv.ParseInt(hr.From, 10, 64)
+	if err != nil {
+		return errors.New("from must be specified as a valid timestamp")
+	}
+	if hr.To != "" {
+		to, err := strconv.ParseInt(hr.To, 10, 64)
+		if err != nil {
+			return errors.New("to must be a valid timestamp")
+		}
+		if to < from {
+			return errors.New("to must not be before from")
+		}
+	}
+	if hr.Limit < 0 {
+		return errors.New("limit must not be negative")
+	}
+	return nil
+}
+
+// HistoryResponse is returned from the PathHistory endpoint and contains one page of
+// chain states in timestamp order. If Next is not empty there are more states in the
+// requested range which are returned when repeating the request with From = Next.
+type HistoryResponse struct {
+	Entries []HistoryEntry `json:"entries"`
+	Next    string         `json:"next,omitempty"`
+}
+
+// HistoryEntry represents the state of the chain after one item was added.
+type HistoryEntry struct {
+	Timestamp string `json:"timestamp"`
+	Sha2_256  []byte `json:"sha2_256"`
+	Sha3_512  []byte `json:"sha3_512"`
+}